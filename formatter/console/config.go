@@ -0,0 +1,118 @@
+package console
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+
+	"github.com/fufuok/gxlog/formatter/text"
+	"github.com/fufuok/gxlog/iface"
+)
+
+// The TimeMode defines how the time of a log is formatted.
+type TimeMode int
+
+// All available time modes here.
+const (
+	// The time is formatted with the TimeLayout.
+	AbsoluteTime TimeMode = iota
+	// The time elapsed since the formatter is created, e.g. "+12.345s".
+	RelativeTime
+	// The time elapsed since the previous log, e.g. "+0.012s".
+	DeltaTime
+)
+
+// The ContextMode defines where the contexts of a log are placed.
+type ContextMode int
+
+// All available context modes here.
+const (
+	// The contexts are right-aligned to the Width on the line of the message.
+	ContextRight ContextMode = iota
+	// The contexts are dimmed on a second line below the message.
+	ContextNextLine
+)
+
+// A Config is used to configure a console formatter.
+type Config struct {
+	// TimeMode specifies how the time of a log is formatted.
+	// If it is not specified, AbsoluteTime is used.
+	TimeMode TimeMode
+	// TimeLayout is the layout of the time in AbsoluteTime mode.
+	// If it is not specified, "15:04:05.000" is used.
+	TimeLayout string
+	// CallerWidth is the width of the caller column. A caller is formatted as
+	// "<last segment of pkg>/<base of file>:<line>", it is left-truncated with
+	// "…" if it is longer. If it is not specified, 24 is used.
+	CallerWidth int
+	// ContextMode specifies where the contexts of a log are placed.
+	// If it is not specified, ContextRight is used.
+	ContextMode ContextMode
+	// Width is the width of a line that contexts are right-aligned to. If the
+	// line is wider, the contexts follow the message with one space.
+	// If it is not specified, 120 is used.
+	Width int
+	// TrimPaths are the prefixes trimmed from the source paths of a stack that
+	// is appended to the message, e.g. when the track level is reached.
+	// If it is nil, the source directory of GOROOT, the source and module
+	// cache directories of GOPATH and the working directory are used.
+	TrimPaths []string
+	// Coloring specifies whether colorization is enabled.
+	Coloring bool
+	// ColorMap is used to remap the color of each level. By default, the color
+	// of a level is text.DefaultColor(level).
+	// The color of a level is left to be unchanged if it is not in the map.
+	ColorMap map[iface.Level]text.Color
+	// MarkedColor is the color of the message of a marked log.
+	// If it is not specified, text.Magenta is used.
+	MarkedColor text.Color
+	// MinBufSize is the initial size of the internal buf of a formatter.
+	// MinBufSize must NOT be negative. If it is not specified, 256 is used.
+	MinBufSize int
+}
+
+func (config *Config) setDefaults() {
+	if config.TimeLayout == "" {
+		config.TimeLayout = "15:04:05.000"
+	}
+	if config.CallerWidth == 0 {
+		config.CallerWidth = 24
+	}
+	if config.Width == 0 {
+		config.Width = 120
+	}
+	if config.TrimPaths == nil {
+		config.TrimPaths = defaultTrimPaths()
+	}
+	if config.MarkedColor == 0 {
+		config.MarkedColor = text.Magenta
+	}
+	if config.MinBufSize == 0 {
+		config.MinBufSize = 256
+	}
+}
+
+// NewConfig returns a Config for the local development with relative time.
+// Colorization is enabled only if text.ColorSupported(os.Stderr) returns true.
+func NewConfig() Config {
+	return Config{
+		TimeMode: RelativeTime,
+		Coloring: text.ColorSupported(os.Stderr),
+	}
+}
+
+func defaultTrimPaths() []string {
+	dirs := []string{filepath.Join(build.Default.GOROOT, "src")}
+	for _, path := range filepath.SplitList(build.Default.GOPATH) {
+		dirs = append(dirs, filepath.Join(path, "pkg", "mod"),
+			filepath.Join(path, "src"))
+	}
+	if wd, err := os.Getwd(); err == nil {
+		dirs = append(dirs, wd)
+	}
+	paths := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		paths = append(paths, filepath.ToSlash(dir)+"/")
+	}
+	return paths
+}
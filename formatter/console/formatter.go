@@ -0,0 +1,296 @@
+// Package console implements a human-friendly console formatter which
+// implements the Formatter. It is intended for the local development, e.g.
+//
+//	07:12:07.235 INFO  db/conn.go:42            connected          host=db1 retry=2
+//	07:12:07.241 ERROR main/main.go:17          failed to query
+//	                                            goroutine 1 [running]:
+//	                                              at main.main (main.go:17)
+//
+// The time, level and caller are formatted in aligned columns and the message
+// follows them. The continuation lines of a multi-line message are indented to
+// the column of the message. A stack appended to the message is pretty-printed
+// with a frame per line and source paths trimmed.
+package console
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fufuok/gxlog/formatter/internal/util"
+	"github.com/fufuok/gxlog/formatter/text"
+	"github.com/fufuok/gxlog/iface"
+)
+
+var (
+	resetSeq = util.SGR(util.ResetCode)
+	boldSeq  = util.SGR(util.BoldCode)
+	dimSeq   = util.SGR(util.DimCode)
+	keySeq   = text.Cyan.Sequence()
+)
+
+var levelNames = []string{
+	iface.Trace: "TRACE",
+	iface.Debug: "DEBUG",
+	iface.Info:  "INFO ",
+	iface.Warn:  "WARN ",
+	iface.Error: "ERROR",
+	iface.Fatal: "FATAL",
+}
+
+// A Formatter implements the interface iface.Formatter.
+//
+// All methods of a Formatter are concurrency safe.
+// A Formatter MUST be created with New.
+type Formatter struct {
+	config    Config
+	levelSeqs []string
+	markedSeq string
+	start     time.Time
+	prev      time.Time
+
+	lock sync.Mutex
+}
+
+// New creates a new Formatter with the config.
+func New(config Config) *Formatter {
+	formatter := &Formatter{start: time.Now()}
+	formatter.setConfig(config)
+	return formatter
+}
+
+// Config returns the Config of the Formatter.
+func (formatter *Formatter) Config() Config {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	return formatter.config
+}
+
+// SetConfig sets the config to the Formatter.
+func (formatter *Formatter) SetConfig(config Config) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	formatter.setConfig(config)
+}
+
+// UpdateConfig calls the fn with the Config of the Formatter, and then sets the
+// returned Config to the Formatter. The fn must NOT be nil.
+//
+// Do NOT call any method of the Formatter or the Logger within the fn,
+// or it may deadlock.
+func (formatter *Formatter) UpdateConfig(fn func(Config) Config) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	formatter.setConfig(fn(formatter.config))
+}
+
+// setConfig sets the config and caches the escape sequences of its colors.
+func (formatter *Formatter) setConfig(config Config) {
+	config.setDefaults()
+	levelSeqs := make([]string, iface.Fatal+1)
+	for level := iface.Trace; level <= iface.Fatal; level++ {
+		color, ok := config.ColorMap[level]
+		if !ok {
+			color = text.DefaultColor(level)
+		}
+		levelSeqs[level] = util.SGR(util.BoldCode, util.ColorCode(int(color), false))
+	}
+	formatter.config = config
+	formatter.levelSeqs = levelSeqs
+	formatter.markedSeq = config.MarkedColor.Sequence()
+}
+
+// Format implements the interface Formatter. It formats a Record.
+func (formatter *Formatter) Format(record *iface.Record) []byte {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	line := &lineBuf{
+		buf:      make([]byte, 0, formatter.config.MinBufSize),
+		coloring: formatter.config.Coloring,
+	}
+	formatter.appendTime(line, record.Time)
+	line.AppendString(" ", "")
+	line.AppendString(levelName(record.Level), formatter.levelSeq(record.Level))
+	line.AppendString(" ", "")
+	line.AppendString(formatter.caller(record), dimSeq)
+	line.AppendString(" ", "")
+	indent := line.width
+
+	msg, stack := splitStack(record.Msg)
+	first, rest := msg, ""
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		first, rest = msg[:i], msg[i+1:]
+	}
+	msgSeq := ""
+	if record.Aux.Marked {
+		msgSeq = formatter.markedSeq
+	}
+	line.AppendString(record.Aux.Prefix+first, msgSeq)
+
+	contexts := record.Aux.Contexts
+	if len(contexts) > 0 && formatter.config.ContextMode == ContextRight {
+		pad := formatter.config.Width - line.width - contextsWidth(contexts)
+		if pad < 1 {
+			pad = 1
+		}
+		line.AppendString(strings.Repeat(" ", pad), "")
+		formatter.appendContexts(line, contexts)
+	}
+	if len(contexts) > 0 && formatter.config.ContextMode == ContextNextLine {
+		line.NewLine(indent)
+		line.coloring = false
+		if formatter.config.Coloring {
+			line.buf = append(line.buf, dimSeq...)
+		}
+		formatter.appendContexts(line, contexts)
+		if formatter.config.Coloring {
+			line.buf = append(line.buf, resetSeq...)
+		}
+		line.coloring = formatter.config.Coloring
+	}
+	for _, str := range splitLines(rest) {
+		line.NewLine(indent)
+		line.AppendString(str, msgSeq)
+	}
+	if stack != "" {
+		formatter.appendStack(line, indent, stack)
+	}
+	return append(line.buf, '\n')
+}
+
+func (formatter *Formatter) appendTime(line *lineBuf, tm time.Time) {
+	var str string
+	switch formatter.config.TimeMode {
+	case RelativeTime:
+		str = formatElapsed(tm.Sub(formatter.start))
+	case DeltaTime:
+		if formatter.prev.IsZero() {
+			formatter.prev = formatter.start
+		}
+		str = formatElapsed(tm.Sub(formatter.prev))
+		formatter.prev = tm
+	default:
+		str = tm.Format(formatter.config.TimeLayout)
+	}
+	line.AppendString(str, dimSeq)
+}
+
+func (formatter *Formatter) caller(record *iface.Record) string {
+	caller := record.File
+	if i := strings.LastIndexByte(caller, '/'); i >= 0 {
+		caller = caller[i+1:]
+	}
+	pkg := record.Pkg
+	if i := strings.LastIndexByte(pkg, '/'); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if pkg != "" {
+		caller = pkg + "/" + caller
+	}
+	caller += ":" + strconv.Itoa(record.Line)
+
+	width := formatter.config.CallerWidth
+	count := utf8.RuneCountInString(caller)
+	if count > width {
+		for ; count > width-1; count-- {
+			_, size := utf8.DecodeRuneInString(caller)
+			caller = caller[size:]
+		}
+		caller = "…" + caller
+		count++
+	}
+	return caller + strings.Repeat(" ", width-count)
+}
+
+func (formatter *Formatter) appendContexts(line *lineBuf, contexts []iface.Context) {
+	for i, context := range contexts {
+		if i > 0 {
+			line.AppendString(" ", "")
+		}
+		line.AppendString(context.Key, keySeq)
+		line.AppendString("="+context.Value, "")
+	}
+}
+
+func (formatter *Formatter) levelSeq(level iface.Level) string {
+	if level >= iface.Trace && level <= iface.Fatal {
+		return formatter.levelSeqs[level]
+	}
+	return boldSeq
+}
+
+// A lineBuf is a buf that keeps the width of its last line.
+type lineBuf struct {
+	buf      []byte
+	width    int
+	coloring bool
+}
+
+// AppendString appends the str to the buf. If the seq is not empty and
+// colorization is enabled, the str is wrapped with the seq and the reset
+// sequence. The str must NOT contain any newline.
+func (line *lineBuf) AppendString(str, seq string) {
+	if line.coloring && seq != "" && str != "" {
+		line.buf = append(line.buf, seq...)
+		line.buf = append(line.buf, str...)
+		line.buf = append(line.buf, resetSeq...)
+	} else {
+		line.buf = append(line.buf, str...)
+	}
+	line.width += utf8.RuneCountInString(str)
+}
+
+// NewLine starts a new line that is indented with the indent.
+func (line *lineBuf) NewLine(indent int) {
+	line.buf = append(line.buf, '\n')
+	for i := 0; i < indent; i++ {
+		line.buf = append(line.buf, ' ')
+	}
+	line.width = indent
+}
+
+func levelName(level iface.Level) string {
+	if level >= iface.Trace && level <= iface.Fatal {
+		return levelNames[level]
+	}
+	return "?????"
+}
+
+// formatElapsed formats the elapsed time in seconds with millisecond precision
+// and right-aligned to the width of 10, e.g. "   +1.024s".
+func formatElapsed(elapsed time.Duration) string {
+	sign := "+"
+	if elapsed < 0 {
+		sign = "-"
+		elapsed = -elapsed
+	}
+	str := sign + strconv.FormatFloat(elapsed.Seconds(), 'f', 3, 64) + "s"
+	if len(str) < 10 {
+		str = strings.Repeat(" ", 10-len(str)) + str
+	}
+	return str
+}
+
+func contextsWidth(contexts []iface.Context) int {
+	width := len(contexts) - 1
+	for _, context := range contexts {
+		width += utf8.RuneCountInString(context.Key) +
+			utf8.RuneCountInString(context.Value) + 1
+	}
+	return width
+}
+
+// splitLines splits the str into lines. A trailing newline is ignored.
+func splitLines(str string) []string {
+	str = strings.TrimSuffix(str, "\n")
+	if str == "" {
+		return nil
+	}
+	return strings.Split(str, "\n")
+}
@@ -0,0 +1,105 @@
+package console_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/formatter/console"
+	"github.com/fufuok/gxlog/formatter/text"
+	"github.com/fufuok/gxlog/iface"
+)
+
+const tmplStack = `goroutine 1 [running]:
+runtime/debug.Stack(0xc000010018, 0x2, 0x2)
+	/usr/local/go/src/runtime/debug/stack.go:24 +0x9f
+github.com/fufuok/gxlog/logger.(*Logger).Log(0xc0000a2000, 0x0, 0x5, 0xc000012345, 0x1, 0x1)
+	/home/test/go/src/github.com/fufuok/gxlog/logger/logger.go:151 +0x1a5
+github.com/foo/bar/db.(*Conn).Query(...)
+	/home/test/proj/db/conn.go:42 +0x25
+created by main.main in goroutine 1
+	/home/test/proj/main.go:10 +0x3f`
+
+var tmplRecord = iface.Record{
+	Time:  time.Date(2018, 8, 1, 7, 12, 7, 235605270, time.UTC),
+	Level: iface.Info,
+	File:  "/home/test/proj/db/conn.go",
+	Line:  42,
+	Pkg:   "github.com/foo/bar/db",
+	Func:  "Query",
+	Msg:   "connected",
+	Aux: iface.Auxiliary{
+		Contexts: []iface.Context{
+			{Key: "host", Value: "db1"},
+			{Key: "retry", Value: "2"},
+		},
+	},
+}
+
+func TestFormat(t *testing.T) {
+	record := tmplRecord
+	testFormat(t, console.New(console.Config{Width: 80}), &record,
+		"07:12:07.235 INFO  db/conn.go:42            connected"+
+			"           host=db1 retry=2\n")
+	testFormat(t, console.New(console.Config{CallerWidth: 10, Width: 1}), &record,
+		"07:12:07.235 INFO  …onn.go:42 connected host=db1 retry=2\n")
+
+	record.Level = iface.Error
+	record.Msg = "failed\nto query\n" + tmplStack
+	record.Aux.Contexts = record.Aux.Contexts[:1]
+	formatter := console.New(console.Config{
+		ContextMode: console.ContextNextLine,
+		TrimPaths:   []string{"/home/test/proj/"},
+	})
+	indent := strings.Repeat(" ", 44)
+	testFormat(t, formatter, &record,
+		"07:12:07.235 ERROR db/conn.go:42            failed\n"+
+			indent+"host=db1\n"+
+			indent+"to query\n"+
+			indent+"goroutine 1 [running]:\n"+
+			indent+"  at db.(*Conn).Query (db/conn.go:42)\n"+
+			indent+"  created by main.main (main.go:10)\n")
+}
+
+func TestRelativeTime(t *testing.T) {
+	record := tmplRecord
+	record.Aux.Contexts = nil
+	formatter := console.New(console.Config{TimeMode: console.DeltaTime})
+	record.Time = time.Now().Add(time.Second)
+	output := string(formatter.Format(&record))
+	if !strings.HasPrefix(output, "   +1.0") {
+		t.Errorf("TestRelativeTime: unexpected output %q", output)
+	}
+	record.Time = record.Time.Add(1500 * time.Millisecond)
+	testFormat(t, formatter, &record,
+		"   +1.500s INFO  db/conn.go:42            connected\n")
+}
+
+func TestColor(t *testing.T) {
+	record := tmplRecord
+	record.Aux.Marked = true
+	formatter := console.New(console.Config{Coloring: true, Width: 1})
+	testFormat(t, formatter, &record,
+		"\033[2m07:12:07.235\033[0m \033[1;34mINFO \033[0m "+
+			"\033[2mdb/conn.go:42           \033[0m \033[35mconnected\033[0m "+
+			"\033[36mhost\033[0m=db1 \033[36mretry\033[0m=2\n")
+
+	formatter.UpdateConfig(func(config console.Config) console.Config {
+		config.ColorMap = map[iface.Level]text.Color{iface.Info: text.TrueColor(255, 136, 0)}
+		config.MarkedColor = text.Color256(208)
+		return config
+	})
+	testFormat(t, formatter, &record,
+		"\033[2m07:12:07.235\033[0m \033[1;38;2;255;136;0mINFO \033[0m "+
+			"\033[2mdb/conn.go:42           \033[0m \033[38;5;208mconnected\033[0m "+
+			"\033[36mhost\033[0m=db1 \033[36mretry\033[0m=2\n")
+}
+
+func testFormat(t *testing.T, formatter iface.Formatter, record *iface.Record,
+	expect string) {
+
+	output := string(formatter.Format(record))
+	if output != expect {
+		t.Errorf("testFormat:\noutput: %q\nexpect: %q", output, expect)
+	}
+}
@@ -0,0 +1,104 @@
+package console
+
+import (
+	"strings"
+)
+
+const stackIndent = 2
+
+// The frames of these packages at the top of a stack are skipped.
+var skippedPkgs = map[string]bool{
+	"runtime/debug":                  true,
+	"github.com/fufuok/gxlog":        true,
+	"github.com/fufuok/gxlog/logger": true,
+}
+
+// splitStack splits the msg into the message and the stack appended to it by
+// the Logger, which is in the format of runtime/debug.Stack.
+func splitStack(msg string) (string, string) {
+	begin := 0
+	for {
+		i := strings.Index(msg[begin:], "\ngoroutine ")
+		if i < 0 {
+			return msg, ""
+		}
+		begin += i + 1
+		header := msg[begin:]
+		if end := strings.IndexByte(header, '\n'); end >= 0 {
+			header = header[:end]
+		}
+		if strings.HasSuffix(header, "]:") {
+			return msg[:begin-1], msg[begin:]
+		}
+	}
+}
+
+// appendStack appends the stack with a frame per line, e.g.
+//
+//	goroutine 1 [running]:
+//	  at main.main (main.go:10)
+//	  created by main.main (main.go:8)
+func (formatter *Formatter) appendStack(line *lineBuf, indent int, stack string) {
+	lines := splitLines(stack)
+	top := true
+	for i := 0; i < len(lines); i++ {
+		fn := lines[i]
+		pos := ""
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") {
+			pos = formatter.trimPos(lines[i+1])
+			i++
+		}
+		if pos == "" {
+			line.NewLine(indent)
+			line.AppendString(fn, dimSeq)
+			continue
+		}
+		prefix := "at "
+		if strings.HasPrefix(fn, "created by ") {
+			prefix = "created by "
+			fn = strings.TrimPrefix(fn, prefix)
+			if end := strings.Index(fn, " in goroutine "); end >= 0 {
+				fn = fn[:end]
+			}
+		} else if end := strings.LastIndexByte(fn, '('); end > 0 {
+			fn = fn[:end]
+		}
+		if top && skippedPkgs[funcPkg(fn)] {
+			continue
+		}
+		top = false
+		line.NewLine(indent + stackIndent)
+		line.AppendString(prefix+shortenFunc(fn)+" ", "")
+		line.AppendString("("+pos+")", dimSeq)
+	}
+}
+
+// trimPos trims the tab, the trim paths and the pc offset of a position line
+// of a stack, e.g. "\t/home/me/proj/main.go:10 +0x25" to "main.go:10".
+func (formatter *Formatter) trimPos(pos string) string {
+	pos = strings.TrimPrefix(pos, "\t")
+	if end := strings.LastIndex(pos, " +0x"); end >= 0 {
+		pos = pos[:end]
+	}
+	for _, path := range formatter.config.TrimPaths {
+		if path != "" && strings.HasPrefix(pos, path) {
+			return pos[len(path):]
+		}
+	}
+	return pos
+}
+
+// funcPkg returns the package path of a full function name.
+func funcPkg(fn string) string {
+	slash := strings.LastIndexByte(fn, '/') + 1
+	if dot := strings.IndexByte(fn[slash:], '.'); dot >= 0 {
+		return fn[:slash+dot]
+	}
+	return fn
+}
+
+// shortenFunc trims the package path except for its last segment of a full
+// function name.
+func shortenFunc(fn string) string {
+	return fn[strings.LastIndexByte(fn, '/')+1:]
+}
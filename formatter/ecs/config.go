@@ -0,0 +1,38 @@
+package ecs
+
+// A Config is used to configure an ecs formatter.
+type Config struct {
+	// ServiceName is the service.name field if it is not empty.
+	ServiceName string
+	// TraceKey is the key of the context that holds the trace.id field. The
+	// context is NOT formatted as a label.
+	// If TraceKey is not specified, "trace_id" is used.
+	TraceKey string
+	// SpanKey is the key of the context that holds the span.id field. The
+	// context is NOT formatted as a label.
+	// If SpanKey is not specified, "span_id" is used.
+	SpanKey string
+	// FileSegs specifies how many segments from last of the File field of a
+	// Record will be formatted. The separator of segment is '/'.
+	// If FileSegs is not specified, 0 is used which means all.
+	FileSegs int
+	// Pooling specifies whether the bytes returned by Formatter.Format come
+	// from a pool. See iface.Releaser for when they are put back and what it
+	// requires of writers.
+	Pooling bool
+	// MinBufSize is the initial size of the internal buf of a formatter.
+	// MinBufSize must NOT be negative. If it is not specified, 512 is used.
+	MinBufSize int
+}
+
+func (config *Config) setDefaults() {
+	if config.TraceKey == "" {
+		config.TraceKey = "trace_id"
+	}
+	if config.SpanKey == "" {
+		config.SpanKey = "span_id"
+	}
+	if config.MinBufSize == 0 {
+		config.MinBufSize = 512
+	}
+}
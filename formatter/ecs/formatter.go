@@ -0,0 +1,211 @@
+// Package ecs implements an ecs formatter which implements the Formatter.
+// It formats a Record as a JSON document of the Elastic Common Schema (ECS) as
+// follows:
+//
+//	@timestamp            the Time in UTC with milliseconds
+//	log.level             the level name, e.g. "info"
+//	message               the Msg
+//	ecs.version           the version of ECS, "1.6.0"
+//	log.origin.file.name  the File
+//	log.origin.file.line  the Line
+//	log.origin.function   the Func
+//	log.logger            the Pkg
+//	service.name          the service name if it is not empty
+//	trace.id, span.id     the contexts of the trace key and the span key
+//	labels                an object of the other contexts and the prefix with
+//	                      the key "prefix", dots in keys are replaced with '_'
+//	tags                  ["marked"] if the Record is marked
+//
+// Fields of empty values are omitted except for @timestamp, log.level and
+// message.
+package ecs
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fufuok/gxlog/formatter/internal/util"
+	"github.com/fufuok/gxlog/iface"
+)
+
+const (
+	ecsVersion = "1.6.0"
+	timeLayout = "2006-01-02T15:04:05.000Z07:00"
+)
+
+var levelNames = []string{
+	iface.Trace: "trace",
+	iface.Debug: "debug",
+	iface.Info:  "info",
+	iface.Warn:  "warn",
+	iface.Error: "error",
+	iface.Fatal: "fatal",
+}
+
+// A Formatter implements the interface iface.Formatter and iface.Releaser.
+//
+// All methods of a Formatter are concurrency safe. Logs are formatted with the
+// current Config of the Formatter without locking, and SetConfig and
+// UpdateConfig replace the Config atomically.
+// A Formatter MUST be created with New.
+type Formatter struct {
+	config atomic.Value // *Config
+
+	lock sync.Mutex // serializes UpdateConfig
+}
+
+// New creates a new Formatter with the config.
+func New(config Config) *Formatter {
+	config.setDefaults()
+	formatter := &Formatter{}
+	formatter.config.Store(&config)
+	return formatter
+}
+
+// Format implements the interface Formatter. It formats a Record.
+// If Config.Pooling is true, the returned bytes come from a pool and they
+// should be released with Release after they are written.
+func (formatter *Formatter) Format(record *iface.Record) []byte {
+	config := formatter.load()
+	var buf []byte
+	if config.Pooling {
+		buf = util.GetBuffer(config.MinBufSize)
+	} else {
+		buf = make([]byte, 0, config.MinBufSize)
+	}
+	buf = append(buf, `{"@timestamp":"`...)
+	buf = record.Time.UTC().AppendFormat(buf, timeLayout)
+	buf = append(buf, `"`...)
+	buf = appendStrField(buf, "log.level", levelName(record.Level))
+	buf = appendStrField(buf, "message", record.Msg)
+	buf = appendStrField(buf, "ecs.version", ecsVersion)
+	if record.File != "" {
+		file := util.LastSegments(record.File, config.FileSegs, '/')
+		buf = appendStrField(buf, "log.origin.file.name", file)
+		buf = append(buf, `,"log.origin.file.line":`...)
+		buf = strconv.AppendInt(buf, int64(record.Line), 10)
+	}
+	if record.Func != "" {
+		buf = appendStrField(buf, "log.origin.function", record.Func)
+	}
+	if record.Pkg != "" {
+		buf = appendStrField(buf, "log.logger", record.Pkg)
+	}
+	if config.ServiceName != "" {
+		buf = appendStrField(buf, "service.name", config.ServiceName)
+	}
+	buf = config.formatContexts(buf, &record.Aux)
+	if record.Aux.Marked {
+		buf = append(buf, `,"tags":["marked"]`...)
+	}
+	buf = append(buf, "}\n"...)
+	if !config.Pooling {
+		return util.Unpooled(buf)
+	}
+	return buf
+}
+
+// Release implements the interface Releaser. It puts the bs returned by Format
+// back to the pool if Config.Pooling was true when the bs was formatted,
+// otherwise it does nothing. The bs must NOT be used after it is released.
+func (formatter *Formatter) Release(bs []byte) {
+	util.ReleaseBuffer(bs)
+}
+
+// Config returns the Config of the Formatter.
+func (formatter *Formatter) Config() Config {
+	return *formatter.load()
+}
+
+// SetConfig sets the config to the Formatter.
+func (formatter *Formatter) SetConfig(config Config) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	config.setDefaults()
+	formatter.config.Store(&config)
+}
+
+// UpdateConfig calls the fn with the Config of the Formatter, and then sets the
+// returned Config to the Formatter. The fn must NOT be nil.
+//
+// Do NOT call any method of the Formatter or the Logger within the fn,
+// or it may deadlock.
+func (formatter *Formatter) UpdateConfig(fn func(Config) Config) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	config := fn(*formatter.load())
+	config.setDefaults()
+	formatter.config.Store(&config)
+}
+
+func (formatter *Formatter) load() *Config {
+	return formatter.config.Load().(*Config)
+}
+
+func (config *Config) formatContexts(buf []byte, aux *iface.Auxiliary) []byte {
+	sep := `,"labels":{`
+	if aux.Prefix != "" {
+		buf = append(buf, sep...)
+		buf = appendLabel(buf, "prefix", aux.Prefix)
+		sep = ","
+	}
+	var traceID, spanID string
+	for _, context := range aux.Contexts {
+		switch context.Key {
+		case config.TraceKey:
+			traceID = context.Value
+		case config.SpanKey:
+			spanID = context.Value
+		default:
+			buf = append(buf, sep...)
+			buf = appendLabel(buf, context.Key, context.Value)
+			sep = ","
+		}
+	}
+	if sep == "," {
+		buf = append(buf, '}')
+	}
+	if traceID != "" {
+		buf = appendStrField(buf, "trace.id", traceID)
+	}
+	if spanID != "" {
+		buf = appendStrField(buf, "span.id", spanID)
+	}
+	return buf
+}
+
+func levelName(level iface.Level) string {
+	if level < iface.Trace || level > iface.Fatal {
+		return "unknown"
+	}
+	return levelNames[level]
+}
+
+func appendStrField(buf []byte, key, value string) []byte {
+	buf = append(buf, `,"`...)
+	buf = append(buf, key...)
+	buf = append(buf, `":"`...)
+	buf = util.EscapeJSON(buf, value)
+	return append(buf, '"')
+}
+
+func appendLabel(buf []byte, key, value string) []byte {
+	buf = append(buf, '"')
+	for {
+		i := strings.IndexByte(key, '.')
+		if i < 0 {
+			break
+		}
+		buf = util.EscapeJSON(buf, key[:i])
+		buf = append(buf, '_')
+		key = key[i+1:]
+	}
+	buf = util.EscapeJSON(buf, key)
+	buf = append(buf, `":"`...)
+	buf = util.EscapeJSON(buf, value)
+	return append(buf, '"')
+}
@@ -0,0 +1,65 @@
+package ecs_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/formatter/ecs"
+	"github.com/fufuok/gxlog/iface"
+)
+
+var tmplRecord = iface.Record{
+	Time:  time.Date(2018, 8, 1, 7, 12, 7, 235605270, time.UTC),
+	Level: iface.Error,
+	File:  "/home/test/src/github.com/fufuok/gxlog/logger.go",
+	Line:  64,
+	Pkg:   "github.com/fufuok/gxlog",
+	Func:  "Test",
+	Msg:   "failed\nstack",
+	Aux: iface.Auxiliary{
+		Prefix: "**** ",
+		Contexts: []iface.Context{
+			{Key: "trace_id", Value: "4bf92f3577b34da6a3ce929d0e0e4736"},
+			{Key: "user.id", Value: "42"},
+		},
+		Marked: true,
+	},
+}
+
+func TestFormat(t *testing.T) {
+	formatter := ecs.New(ecs.Config{ServiceName: "app", FileSegs: 1})
+	expect := `{"@timestamp":"2018-08-01T07:12:07.235Z","log.level":"error",` +
+		`"message":"failed\nstack","ecs.version":"1.6.0",` +
+		`"log.origin.file.name":"logger.go","log.origin.file.line":64,` +
+		`"log.origin.function":"Test","log.logger":"github.com/fufuok/gxlog",` +
+		`"service.name":"app","labels":{"prefix":"**** ","user_id":"42"},` +
+		`"trace.id":"4bf92f3577b34da6a3ce929d0e0e4736","tags":["marked"]}` + "\n"
+	output := formatter.Format(&tmplRecord)
+	if string(output) != expect {
+		t.Errorf("TestFormat:\noutput: %q\nexpect: %q", output, expect)
+	}
+
+	record := iface.Record{Time: tmplRecord.Time, Level: iface.Info, Msg: "done"}
+	output = formatter.Format(&record)
+	expect = `{"@timestamp":"2018-08-01T07:12:07.235Z","log.level":"info",` +
+		`"message":"done","ecs.version":"1.6.0","service.name":"app"}` + "\n"
+	if string(output) != expect {
+		t.Errorf("TestFormat:\noutput: %q\nexpect: %q", output, expect)
+	}
+	if !json.Valid(output) {
+		t.Errorf("TestFormat: invalid json: %s", output)
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	formatter := ecs.New(ecs.Config{Pooling: true})
+	record := tmplRecord
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			formatter.Release(formatter.Format(&record))
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package gelf
+
+import (
+	"os"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+// A Config is used to configure a gelf formatter.
+type Config struct {
+	// Host is the host field of GELF messages.
+	// If Host is not specified, os.Hostname() is used.
+	Host string
+	// FileSegs specifies how many segments from last of the File field of a
+	// Record will be formatted. The separator of segment is '/'.
+	// If FileSegs is not specified, 0 is used which means all.
+	FileSegs int
+	// PkgSegs specifies how many segments from last of the Pkg field of a
+	// Record will be formatted. The separator of segment is '/'.
+	// If PkgSegs is not specified, 0 is used which means all.
+	PkgSegs int
+	// FuncSegs specifies how many segments from last of the Func field of a
+	// Record will be formatted. The separator of segment is '.'.
+	// If FuncSegs is not specified, 0 is used which means all.
+	FuncSegs int
+	// LevelMap is used to remap the level field, which is a syslog severity,
+	// of levels. The severity of a level is left to be unchanged if it is not
+	// in the map. The default mapping is as the follows:
+	//   Trace: 7 (debug)
+	//   Debug: 7 (debug)
+	//   Info:  6 (informational)
+	//   Warn:  4 (warning)
+	//   Error: 3 (error)
+	//   Fatal: 2 (critical)
+	LevelMap map[iface.Level]int
+	// Pooling specifies whether the bytes returned by Formatter.Format come
+	// from a pool. See iface.Releaser for when they are put back and what it
+	// requires of writers.
+	Pooling bool
+	// MinBufSize is the initial size of the internal buf of a formatter.
+	// MinBufSize must NOT be negative. If it is not specified, 512 is used.
+	MinBufSize int
+}
+
+func (config *Config) setDefaults() {
+	if config.Host == "" {
+		config.Host, _ = os.Hostname()
+	}
+	if config.MinBufSize == 0 {
+		config.MinBufSize = 512
+	}
+}
+
+// NewConfig returns a Config with the last segment of the File, Pkg and Func
+// fields formatted.
+func NewConfig() Config {
+	return Config{
+		FileSegs: 1,
+		PkgSegs:  1,
+		FuncSegs: 1,
+	}
+}
@@ -0,0 +1,209 @@
+// Package gelf implements a gelf formatter which implements the Formatter.
+// It formats a Record as a GELF 1.1 message of Graylog as follows:
+//
+//	short_message  the first line of the Msg
+//	full_message   the Msg if it has multiple lines, e.g. with the stack
+//	               output at the track level
+//	timestamp      the Time in seconds with microseconds as the fraction
+//	level          the syslog severity of the Level
+//	_file, _line, _pkg, _func, _prefix, _marked
+//	_<key>         a context, the characters of the key other than letters,
+//	               digits, '_', '.' and '-' are replaced with '_'
+//
+// Fields of empty values are omitted except for short_message.
+package gelf
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fufuok/gxlog/formatter/internal/util"
+	"github.com/fufuok/gxlog/iface"
+)
+
+var defaultSeverities = []int{
+	iface.Trace: 7,
+	iface.Debug: 7,
+	iface.Info:  6,
+	iface.Warn:  4,
+	iface.Error: 3,
+	iface.Fatal: 2,
+}
+
+// A Formatter implements the interface iface.Formatter and iface.Releaser.
+//
+// All methods of a Formatter are concurrency safe. Logs are formatted with the
+// current Config of the Formatter without locking, and SetConfig and
+// UpdateConfig replace the Config atomically.
+// A Formatter MUST be created with New.
+type Formatter struct {
+	snapshot atomic.Value // *snapshot
+
+	lock sync.Mutex // serializes UpdateConfig
+}
+
+// A snapshot is the immutable state of a Formatter.
+type snapshot struct {
+	config     Config
+	severities []int
+}
+
+// New creates a new Formatter with the config.
+func New(config Config) *Formatter {
+	formatter := &Formatter{}
+	formatter.setConfig(config)
+	return formatter
+}
+
+// Format implements the interface Formatter. It formats a Record.
+// If Config.Pooling is true, the returned bytes come from a pool and they
+// should be released with Release after they are written.
+func (formatter *Formatter) Format(record *iface.Record) []byte {
+	snap := formatter.load()
+	config := &snap.config
+	var buf []byte
+	if config.Pooling {
+		buf = util.GetBuffer(config.MinBufSize)
+	} else {
+		buf = make([]byte, 0, config.MinBufSize)
+	}
+	buf = append(buf, `{"version":"1.1","host":"`...)
+	buf = util.EscapeJSON(buf, config.Host)
+	buf = append(buf, `","short_message":"`...)
+	msg := record.Msg
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		buf = util.EscapeJSON(buf, strings.TrimRight(msg[:i], "\r"))
+		buf = append(buf, `","full_message":"`...)
+	}
+	buf = util.EscapeJSON(buf, msg)
+	buf = append(buf, `","timestamp":`...)
+	buf = appendTimestamp(buf, record)
+	if record.Level >= iface.Trace && record.Level <= iface.Fatal {
+		buf = append(buf, `,"level":`...)
+		buf = strconv.AppendInt(buf, int64(snap.severities[record.Level]), 10)
+	}
+	if record.File != "" {
+		file := util.LastSegments(record.File, config.FileSegs, '/')
+		buf = appendStrField(buf, "_file", file)
+		buf = append(buf, `,"_line":`...)
+		buf = strconv.AppendInt(buf, int64(record.Line), 10)
+	}
+	if record.Pkg != "" {
+		pkg := util.LastSegments(record.Pkg, config.PkgSegs, '/')
+		buf = appendStrField(buf, "_pkg", pkg)
+	}
+	if record.Func != "" {
+		fn := util.LastSegments(record.Func, config.FuncSegs, '.')
+		buf = appendStrField(buf, "_func", fn)
+	}
+	if record.Aux.Prefix != "" {
+		buf = appendStrField(buf, "_prefix", record.Aux.Prefix)
+	}
+	if record.Aux.Marked {
+		buf = append(buf, `,"_marked":true`...)
+	}
+	for _, context := range record.Aux.Contexts {
+		buf = appendContext(buf, context.Key, context.Value)
+	}
+	buf = append(buf, "}\n"...)
+	if !config.Pooling {
+		return util.Unpooled(buf)
+	}
+	return buf
+}
+
+// Release implements the interface Releaser. It puts the bs returned by Format
+// back to the pool if Config.Pooling was true when the bs was formatted,
+// otherwise it does nothing. The bs must NOT be used after it is released.
+func (formatter *Formatter) Release(bs []byte) {
+	util.ReleaseBuffer(bs)
+}
+
+// Config returns the Config of the Formatter.
+func (formatter *Formatter) Config() Config {
+	return formatter.load().config
+}
+
+// SetConfig sets the config to the Formatter.
+func (formatter *Formatter) SetConfig(config Config) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	formatter.setConfig(config)
+}
+
+// UpdateConfig calls the fn with the Config of the Formatter, and then sets the
+// returned Config to the Formatter. The fn must NOT be nil.
+//
+// Do NOT call any method of the Formatter or the Logger within the fn,
+// or it may deadlock.
+func (formatter *Formatter) UpdateConfig(fn func(Config) Config) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	formatter.setConfig(fn(formatter.load().config))
+}
+
+func (formatter *Formatter) load() *snapshot {
+	return formatter.snapshot.Load().(*snapshot)
+}
+
+func (formatter *Formatter) setConfig(config Config) {
+	config.setDefaults()
+	severities := append([]int(nil), defaultSeverities...)
+	for level, severity := range config.LevelMap {
+		if level >= iface.Trace && level <= iface.Fatal {
+			severities[level] = severity
+		}
+	}
+	formatter.snapshot.Store(&snapshot{
+		config:     config,
+		severities: severities,
+	})
+}
+
+func appendTimestamp(buf []byte, record *iface.Record) []byte {
+	nano := record.Time.UnixNano()
+	buf = strconv.AppendInt(buf, nano/1e9, 10)
+	micro := nano % 1e9 / 1e3
+	if micro < 0 {
+		micro = -micro
+	}
+	// the leading '1' of the padded micro is replaced with the '.'
+	dot := len(buf)
+	buf = strconv.AppendInt(buf, micro+1e6, 10)
+	buf[dot] = '.'
+	return buf
+}
+
+func appendStrField(buf []byte, key, value string) []byte {
+	buf = append(buf, `,"`...)
+	buf = append(buf, key...)
+	buf = append(buf, `":"`...)
+	buf = util.EscapeJSON(buf, value)
+	return append(buf, '"')
+}
+
+// appendContext appends a context as an additional field. The name "_id" is
+// reserved by GELF, so the context "id" becomes "_id_".
+func appendContext(buf []byte, key, value string) []byte {
+	buf = append(buf, `,"_`...)
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '_', c == '.', c == '-':
+		default:
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	if key == "id" {
+		buf = append(buf, '_')
+	}
+	buf = append(buf, `":"`...)
+	buf = util.EscapeJSON(buf, value)
+	return append(buf, '"')
+}
@@ -0,0 +1,72 @@
+package gelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/formatter/gelf"
+	"github.com/fufuok/gxlog/iface"
+)
+
+var tmplRecord = iface.Record{
+	Time:  time.Unix(1533107527, 235605270),
+	Level: iface.Error,
+	File:  "/home/test/src/github.com/fufuok/gxlog/logger.go",
+	Line:  64,
+	Pkg:   "github.com/fufuok/gxlog",
+	Func:  "Test",
+	Msg:   "testing\ngoroutine 1 [running]:",
+	Aux: iface.Auxiliary{
+		Prefix: "**** ",
+		Contexts: []iface.Context{
+			{Key: "id", Value: "1"},
+			{Key: "req id", Value: `"abc"`},
+		},
+		Marked: true,
+	},
+}
+
+func TestFormat(t *testing.T) {
+	formatter := gelf.New(gelf.Config{
+		Host:     "host",
+		FileSegs: 1,
+		PkgSegs:  1,
+	})
+	expect := `{"version":"1.1","host":"host","short_message":"testing",` +
+		`"full_message":"testing\ngoroutine 1 [running]:",` +
+		`"timestamp":1533107527.235605,"level":3,"_file":"logger.go","_line":64,` +
+		`"_pkg":"gxlog","_func":"Test","_prefix":"**** ","_marked":true,` +
+		`"_id_":"1","_req_id":"\"abc\""}` + "\n"
+	if output := string(formatter.Format(&tmplRecord)); output != expect {
+		t.Errorf("TestFormat:\noutput: %q\nexpect: %q", output, expect)
+	}
+
+	formatter.UpdateConfig(func(config gelf.Config) gelf.Config {
+		config.LevelMap = map[iface.Level]int{iface.Info: 5}
+		return config
+	})
+	record := iface.Record{
+		Time:  time.Unix(1533107527, 0),
+		Level: iface.Info,
+		Msg:   "testing",
+	}
+	expect = `{"version":"1.1","host":"host","short_message":"testing",` +
+		`"timestamp":1533107527.000000,"level":5}` + "\n"
+	if output := string(formatter.Format(&record)); output != expect {
+		t.Errorf("TestFormat:\noutput: %q\nexpect: %q", output, expect)
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	config := gelf.NewConfig()
+	config.Pooling = true
+	formatter := gelf.New(config)
+	record := tmplRecord
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			formatter.Release(formatter.Format(&record))
+		}
+	})
+}
@@ -1,4 +1,4 @@
-package json
+package util
 
 import (
 	"fmt"
@@ -14,7 +14,8 @@ func init() {
 	}
 }
 
-func escape(buf []byte, str string) []byte {
+// EscapeJSON appends the str to the buf with characters escaped as a JSON string.
+func EscapeJSON(buf []byte, str string) []byte {
 	for i := 0; i < len(str); i++ {
 		b := str[i]
 		if b < ctrlCharCount {
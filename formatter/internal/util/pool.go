@@ -0,0 +1,59 @@
+package util
+
+import (
+	"sync"
+)
+
+// maxPooledSize is the max capacity of a buffer that is put back to the pool,
+// so that a huge log does not pin a huge buffer.
+const maxPooledSize = 64 << 10
+
+// Buffers are pooled as *[]byte to avoid allocating when they are put back.
+// The holders of buffers that are taken out are pooled for the same reason.
+var (
+	bufPool    sync.Pool
+	holderPool sync.Pool
+)
+
+// GetBuffer returns an empty buffer with at least the size of capacity from
+// the pool.
+func GetBuffer(size int) []byte {
+	if holder, ok := bufPool.Get().(*[]byte); ok {
+		buf := *holder
+		*holder = nil
+		holderPool.Put(holder)
+		if cap(buf) >= size {
+			return buf[:0]
+		}
+	}
+	return make([]byte, 0, size)
+}
+
+// Unpooled returns the buf, which does NOT come from the pool, as a full slice,
+// so that ReleaseBuffer will NOT put it back to the pool.
+func Unpooled(buf []byte) []byte {
+	return buf[:len(buf):len(buf)]
+}
+
+// ReleaseBuffer puts the buf returned by a Format back to the pool unless it is
+// a full slice, which is either returned by Unpooled or a buffer from the pool
+// that happens to be full and is left to the garbage collector.
+func ReleaseBuffer(buf []byte) {
+	if cap(buf) > len(buf) {
+		PutBuffer(buf)
+	}
+}
+
+// PutBuffer puts the buf back to the pool. The buf must NOT be used after it
+// is put back.
+func PutBuffer(buf []byte) {
+	if cap(buf) == 0 || cap(buf) > maxPooledSize {
+		return
+	}
+	holder, ok := holderPool.Get().(*[]byte)
+	if !ok {
+		holder = new([]byte)
+	}
+	*holder = buf[:0]
+	bufPool.Put(holder)
+}
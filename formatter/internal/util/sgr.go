@@ -0,0 +1,55 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SGR parameters of the attributes here.
+const (
+	ResetCode     = "0"
+	BoldCode      = "1"
+	DimCode       = "2"
+	ItalicCode    = "3"
+	UnderlineCode = "4"
+	BlinkCode     = "5"
+	ReverseCode   = "7"
+)
+
+// Flags of a color that is NOT a basic or bright one. The rest bits of such a
+// color are the index in the 256-color palette or the 24-bit RGB.
+const (
+	Color256Flag  = 0x1 << 24
+	TrueColorFlag = 0x1 << 25
+)
+
+// ColorCode returns the SGR parameters of the color as a foreground color, or
+// as a background color if bg is true. A basic or bright color is its SGR
+// parameter as a foreground color, e.g. 31 for red.
+func ColorCode(color int, bg bool) string {
+	switch {
+	case color&TrueColorFlag != 0:
+		prefix := "38;2;"
+		if bg {
+			prefix = "48;2;"
+		}
+		return fmt.Sprintf("%s%d;%d;%d", prefix, color>>16&0xff, color>>8&0xff,
+			color&0xff)
+	case color&Color256Flag != 0:
+		prefix := "38;5;"
+		if bg {
+			prefix = "48;5;"
+		}
+		return fmt.Sprintf("%s%d", prefix, color&0xff)
+	case bg && color != 0:
+		return strconv.Itoa(color + 10)
+	}
+	return strconv.Itoa(color)
+}
+
+// SGR returns the ANSI escape sequence that sets the SGR parameters, which are
+// separated by ';' in the sequence.
+func SGR(codes ...string) string {
+	return "\033[" + strings.Join(codes, ";") + "m"
+}
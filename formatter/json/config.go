@@ -1,5 +1,9 @@
 package json
 
+import (
+	"time"
+)
+
 // The OmitBits defines the flag type that is used to omit fields of a Record.
 type OmitBits int
 
@@ -19,6 +23,59 @@ const (
 	Aux = Prefix | Context | Mark
 )
 
+// Special time layouts, with which the time is formatted as a number of the
+// elapsed units since the Unix epoch.
+const (
+	EpochSeconds = "epoch_s"
+	EpochMillis  = "epoch_ms"
+	EpochNanos   = "epoch_ns"
+)
+
+// The LevelStyle defines the type of representation of levels.
+type LevelStyle int
+
+// All available level styles here.
+const (
+	// A level is formatted as a single character, e.g. "I".
+	LevelChar LevelStyle = iota
+	// A level is formatted as its full name, e.g. "INFO".
+	LevelFull
+	// A level is formatted as its numeric value, e.g. 3.
+	LevelNumeric
+)
+
+// The ContextStyle defines the type of representation of contexts.
+type ContextStyle int
+
+// All available context styles here.
+const (
+	// Contexts are formatted as an array of single-key objects, e.g.
+	// "contexts":[{"k1":"v1"},{"k2":"v2"}].
+	ContextArray ContextStyle = iota
+	// Contexts are formatted as a nested object, e.g.
+	// "contexts":{"k1":"v1","k2":"v2"}.
+	ContextObject
+	// Contexts are formatted as top-level keys, e.g. "k1":"v1","k2":"v2".
+	// A context with the same key as a field of a Record results in duplicate
+	// keys.
+	ContextFlat
+)
+
+// Keys specifies the key names of the fields of a Record. A key name that is
+// not specified is left to be the default one.
+type Keys struct {
+	Time     string // "time"
+	Level    string // "level"
+	File     string // "file"
+	Line     string // "line"
+	Pkg      string // "pkg"
+	Func     string // "func"
+	Msg      string // "msg"
+	Prefix   string // "prefix"
+	Contexts string // "contexts"
+	Marked   string // "marked"
+}
+
 // A Config is used to configure a json formatter.
 type Config struct {
 	// FileSegs specifies how many segments from last of the File field of a
@@ -38,15 +95,51 @@ type Config struct {
 	// OmitEmpty specifies which fields of a Record will be omitted when they are
 	// the zero value of their type.
 	OmitEmpty OmitBits
+	// Keys specifies the key names of the fields of a Record.
+	Keys Keys
+	// TimeLayout is the layout of the time, see time.Time.Format. It can also be
+	// EpochSeconds, EpochMillis or EpochNanos.
+	// If TimeLayout is not specified, time.RFC3339 is used.
+	TimeLayout string
+	// LevelStyle specifies how levels are formatted.
+	// If LevelStyle is not specified, LevelChar is used.
+	LevelStyle LevelStyle
+	// ContextStyle specifies how contexts are formatted.
+	// If ContextStyle is not specified, ContextArray is used.
+	ContextStyle ContextStyle
+	// Pooling specifies whether the bytes returned by Formatter.Format come
+	// from a pool. See iface.Releaser for when they are put back and what it
+	// requires of writers.
+	Pooling bool
 	// MinBufSize is the initial size of the internal buf of a formatter.
 	// MinBufSize must NOT be negative. If it is not specified, 384 is used.
 	MinBufSize int
 }
 
 func (config *Config) setDefaults() {
+	if config.TimeLayout == "" {
+		config.TimeLayout = time.RFC3339
+	}
 	if config.MinBufSize == 0 {
 		config.MinBufSize = 384
 	}
+	keys := &config.Keys
+	setDefault(&keys.Time, "time")
+	setDefault(&keys.Level, "level")
+	setDefault(&keys.File, "file")
+	setDefault(&keys.Line, "line")
+	setDefault(&keys.Pkg, "pkg")
+	setDefault(&keys.Func, "func")
+	setDefault(&keys.Msg, "msg")
+	setDefault(&keys.Prefix, "prefix")
+	setDefault(&keys.Contexts, "contexts")
+	setDefault(&keys.Marked, "marked")
+}
+
+func setDefault(key *string, name string) {
+	if *key == "" {
+		*key = name
+	}
 }
 
 func NewConfig() Config {
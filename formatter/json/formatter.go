@@ -4,12 +4,22 @@ package json
 import (
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fufuok/gxlog/formatter/internal/util"
 	"github.com/fufuok/gxlog/iface"
 )
 
+var levelDesc = []string{
+	iface.Trace: "TRACE",
+	iface.Debug: "DEBUG",
+	iface.Info:  "INFO",
+	iface.Warn:  "WARN",
+	iface.Error: "ERROR",
+	iface.Fatal: "FATAL",
+}
+
 var levelDescChar = []string{
 	iface.Trace: "T",
 	iface.Debug: "D",
@@ -19,75 +29,89 @@ var levelDescChar = []string{
 	iface.Fatal: "F",
 }
 
-// A Formatter implements the interface iface.Formatter.
+// A Formatter implements the interface iface.Formatter and iface.Releaser.
 //
-// All methods of a Formatter are concurrency safe.
+// All methods of a Formatter are concurrency safe. Logs are formatted with the
+// current Config of the Formatter without locking, and SetConfig and
+// UpdateConfig replace the Config atomically.
 // A Formatter MUST be created with New.
 type Formatter struct {
-	config Config
+	config atomic.Value // *Config
 
-	lock sync.Mutex
+	lock sync.Mutex // serializes UpdateConfig
 }
 
 // New creates a new Formatter with the config.
 func New(config Config) *Formatter {
 	config.setDefaults()
-	formatter := &Formatter{
-		config: config,
-	}
+	formatter := &Formatter{}
+	formatter.config.Store(&config)
 	return formatter
 }
 
 // Format implements the interface Formatter. It formats a Record.
+// If Config.Pooling is true, the returned bytes come from a pool and they
+// should be released with Release after they are written.
 func (formatter *Formatter) Format(record *iface.Record) []byte {
-	formatter.lock.Lock()
-	defer formatter.lock.Unlock()
-
-	buf := make([]byte, 0, formatter.config.MinBufSize)
+	config := formatter.load()
+	var buf []byte
+	if config.Pooling {
+		buf = util.GetBuffer(config.MinBufSize)
+	} else {
+		buf = make([]byte, 0, config.MinBufSize)
+	}
 	sep := ""
 	buf = append(buf, "{"...)
-	if formatter.config.Omit&Time == 0 {
-		buf = formatStrField(buf, sep, "time",
-			record.Time.Format(time.RFC3339), false)
+	keys := &config.Keys
+	if config.Omit&Time == 0 {
+		buf = config.formatTime(buf, sep, record.Time)
 		sep = ","
 	}
-	if formatter.config.Omit&Level == 0 {
-		buf = formatStrField(buf, sep, "level", levelDescChar[record.Level], false)
+	if config.Omit&Level == 0 {
+		buf = config.formatLevel(buf, sep, record.Level)
 		sep = ","
 	}
-	if formatter.config.Omit&File == 0 {
-		file := util.LastSegments(record.File, formatter.config.FileSegs, '/')
-		buf = formatStrField(buf, sep, "file", file, true)
+	if config.Omit&File == 0 {
+		file := util.LastSegments(record.File, config.FileSegs, '/')
+		buf = formatStrField(buf, sep, keys.File, file, true)
 		sep = ","
 	}
-	if formatter.config.Omit&Line == 0 {
-		buf = formatIntField(buf, sep, "line", record.Line)
+	if config.Omit&Line == 0 {
+		buf = formatIntField(buf, sep, keys.Line, int64(record.Line))
 		sep = ","
 	}
-	if formatter.config.Omit&Pkg == 0 {
-		pkg := util.LastSegments(record.Pkg, formatter.config.PkgSegs, '/')
-		buf = formatStrField(buf, sep, "pkg", pkg, false)
+	if config.Omit&Pkg == 0 {
+		pkg := util.LastSegments(record.Pkg, config.PkgSegs, '/')
+		buf = formatStrField(buf, sep, keys.Pkg, pkg, false)
 		sep = ","
 	}
-	if formatter.config.Omit&Func == 0 {
-		fn := util.LastSegments(record.Func, formatter.config.FuncSegs, '.')
-		buf = formatStrField(buf, sep, "func", fn, false)
+	if config.Omit&Func == 0 {
+		fn := util.LastSegments(record.Func, config.FuncSegs, '.')
+		buf = formatStrField(buf, sep, keys.Func, fn, false)
 		sep = ","
 	}
-	if formatter.config.Omit&Msg == 0 {
-		buf = formatStrField(buf, sep, "msg", record.Msg, true)
+	if config.Omit&Msg == 0 {
+		buf = formatStrField(buf, sep, keys.Msg, record.Msg, true)
 		sep = ","
 	}
-	buf = formatter.formatAux(buf, sep, &record.Aux)
-	return append(buf, "}\n"...)
+	buf = config.formatAux(buf, sep, &record.Aux)
+	buf = append(buf, "}\n"...)
+	if !config.Pooling {
+		return util.Unpooled(buf)
+	}
+	return buf
+}
+
+// Release implements the interface Releaser. It puts the bs returned by Format
+// back to the pool if Config.Pooling was true when the bs was formatted,
+// otherwise it does nothing. The bs must NOT be used after it is released.
+func (formatter *Formatter) Release(bs []byte) {
+	util.ReleaseBuffer(bs)
 }
 
 // Config returns the Config of the Formatter.
 func (formatter *Formatter) Config() Config {
-	formatter.lock.Lock()
-	defer formatter.lock.Unlock()
-
-	return formatter.config
+	return *formatter.load()
 }
 
 // SetConfig sets the config to the Formatter.
@@ -96,7 +120,7 @@ func (formatter *Formatter) SetConfig(config Config) {
 	defer formatter.lock.Unlock()
 
 	config.setDefaults()
-	formatter.config = config
+	formatter.config.Store(&config)
 }
 
 // UpdateConfig calls the fn with the Config of the Formatter, and then sets the
@@ -108,52 +132,98 @@ func (formatter *Formatter) UpdateConfig(fn func(Config) Config) {
 	formatter.lock.Lock()
 	defer formatter.lock.Unlock()
 
-	formatter.config = fn(formatter.config)
+	config := fn(*formatter.load())
+	config.setDefaults()
+	formatter.config.Store(&config)
+}
+
+func (formatter *Formatter) load() *Config {
+	return formatter.config.Load().(*Config)
+}
+
+func (config *Config) formatTime(buf []byte, sep string, clock time.Time) []byte {
+	key := config.Keys.Time
+	switch config.TimeLayout {
+	case EpochSeconds:
+		return formatIntField(buf, sep, key, clock.Unix())
+	case EpochMillis:
+		return formatIntField(buf, sep, key, clock.UnixNano()/int64(time.Millisecond))
+	case EpochNanos:
+		return formatIntField(buf, sep, key, clock.UnixNano())
+	}
+	buf = formatKey(buf, sep, key)
+	buf = append(buf, '"')
+	buf = clock.AppendFormat(buf, config.TimeLayout)
+	return append(buf, '"')
+}
+
+func (config *Config) formatLevel(buf []byte, sep string, level iface.Level) []byte {
+	key := config.Keys.Level
+	switch config.LevelStyle {
+	case LevelNumeric:
+		return formatIntField(buf, sep, key, int64(level))
+	case LevelFull:
+		return formatStrField(buf, sep, key, levelDesc[level], false)
+	}
+	return formatStrField(buf, sep, key, levelDescChar[level], false)
 }
 
-func (formatter *Formatter) formatAux(buf []byte, sep string,
+func (config *Config) formatAux(buf []byte, sep string,
 	aux *iface.Auxiliary) []byte {
 
-	if formatter.config.Omit&Aux == Aux {
+	if config.Omit&Aux == Aux {
 		return buf
 	}
-	if formatter.config.OmitEmpty&Aux == Aux &&
+	if config.OmitEmpty&Aux == Aux &&
 		aux.Prefix == "" && len(aux.Contexts) == 0 && !aux.Marked {
 		return buf
 	}
-	buf = append(buf, sep...)
-	sep = ""
-	// buf = append(buf, `"Aux":{`...)
-	if formatter.config.Omit&Prefix == 0 &&
-		!(formatter.config.OmitEmpty&Prefix != 0 && aux.Prefix == "") {
-		buf = formatStrField(buf, sep, "prefix", aux.Prefix, true)
+	if config.Omit&Prefix == 0 &&
+		!(config.OmitEmpty&Prefix != 0 && aux.Prefix == "") {
+		buf = formatStrField(buf, sep, config.Keys.Prefix, aux.Prefix, true)
 		sep = ","
 	}
-	if formatter.config.Omit&Context == 0 &&
-		!(formatter.config.OmitEmpty&Context != 0 && len(aux.Contexts) == 0) {
-		buf = formatContexts(buf, sep, aux.Contexts)
-		sep = ","
+	if config.Omit&Context == 0 &&
+		!(config.OmitEmpty&Context != 0 && len(aux.Contexts) == 0) {
+		buf = config.formatContexts(buf, sep, aux.Contexts)
+		if config.ContextStyle != ContextFlat || len(aux.Contexts) > 0 {
+			sep = ","
+		}
 	}
-	if formatter.config.Omit&Mark == 0 &&
-		!(formatter.config.OmitEmpty&Mark != 0 && !aux.Marked) {
-		buf = formatBoolField(buf, sep, "marked", aux.Marked)
+	if config.Omit&Mark == 0 &&
+		!(config.OmitEmpty&Mark != 0 && !aux.Marked) {
+		buf = formatBoolField(buf, sep, config.Keys.Marked, aux.Marked)
 	}
-	// return append(buf, "}"...)
 	return buf
 }
 
-func formatContexts(buf []byte, sep string, contexts []iface.Context) []byte {
-	buf = append(buf, sep...)
-	sep = ""
-	if len(contexts) == 0 {
-		return append(buf, `"contexts":[]`...)
+func (config *Config) formatContexts(buf []byte, sep string,
+	contexts []iface.Context) []byte {
+
+	key := config.Keys.Contexts
+	switch config.ContextStyle {
+	case ContextObject:
+		buf = formatKey(buf, sep, key)
+		buf = append(buf, "{"...)
+		sep = ""
+		for _, context := range contexts {
+			buf = formatStrField(buf, sep, context.Key, context.Value, true)
+			sep = ","
+		}
+		return append(buf, "}"...)
+	case ContextFlat:
+		for _, context := range contexts {
+			buf = formatStrField(buf, sep, context.Key, context.Value, true)
+			sep = ","
+		}
+		return buf
 	}
-	buf = append(buf, `"contexts":[`...)
+	buf = formatKey(buf, sep, key)
+	buf = append(buf, "["...)
+	sep = ""
 	for _, context := range contexts {
 		buf = append(buf, sep...)
 		buf = append(buf, "{"...)
-		// buf = formatStrField(buf, "", "Key", context.Key, true)
-		// buf = formatStrField(buf, ",", "Value", context.Value, true)
 		buf = formatStrField(buf, "", context.Key, context.Value, true)
 		buf = append(buf, "}"...)
 		sep = ","
@@ -161,32 +231,32 @@ func formatContexts(buf []byte, sep string, contexts []iface.Context) []byte {
 	return append(buf, "]"...)
 }
 
-func formatStrField(buf []byte, sep, key, value string, esc bool) []byte {
+// formatKey appends the sep and the escaped key followed by a colon.
+func formatKey(buf []byte, sep, key string) []byte {
 	buf = append(buf, sep...)
 	buf = append(buf, `"`...)
-	buf = append(buf, key...)
-	buf = append(buf, `":"`...)
+	buf = util.EscapeJSON(buf, key)
+	return append(buf, `":`...)
+}
+
+func formatStrField(buf []byte, sep, key, value string, esc bool) []byte {
+	buf = formatKey(buf, sep, key)
+	buf = append(buf, `"`...)
 	if esc {
-		buf = escape(buf, value)
+		buf = util.EscapeJSON(buf, value)
 	} else {
 		buf = append(buf, value...)
 	}
 	return append(buf, `"`...)
 }
 
-func formatIntField(buf []byte, sep, key string, value int) []byte {
-	buf = append(buf, sep...)
-	buf = append(buf, `"`...)
-	buf = append(buf, key...)
-	buf = append(buf, `":`...)
-	return strconv.AppendInt(buf, int64(value), 10)
+func formatIntField(buf []byte, sep, key string, value int64) []byte {
+	buf = formatKey(buf, sep, key)
+	return strconv.AppendInt(buf, value, 10)
 }
 
 func formatBoolField(buf []byte, sep, key string, value bool) []byte {
-	buf = append(buf, sep...)
-	buf = append(buf, `"`...)
-	buf = append(buf, key...)
-	buf = append(buf, `":`...)
+	buf = formatKey(buf, sep, key)
 	if value {
 		return append(buf, "true"...)
 	}
@@ -0,0 +1,147 @@
+package json_test
+
+import (
+	stdjson "encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/formatter/json"
+	"github.com/fufuok/gxlog/iface"
+)
+
+var tmplRecord = iface.Record{
+	Time:  time.Date(2018, 8, 1, 7, 12, 7, 235605270, time.UTC),
+	Level: iface.Info,
+	File:  "/home/test/src/github.com/fufuok/gxlog/logger.go",
+	Line:  64,
+	Pkg:   "github.com/fufuok/gxlog",
+	Func:  "Test",
+	Msg:   "testing",
+	Aux: iface.Auxiliary{
+		Contexts: []iface.Context{
+			{Key: "k1", Value: "v1"},
+			{Key: `"k2"`, Value: "v2"},
+		},
+	},
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		Config json.Config
+		Expect string
+	}{
+		{
+			Config: json.NewConfig(),
+			Expect: `{"time":"2018-08-01T07:12:07Z","level":"I","file":"logger.go",` +
+				`"line":64,"pkg":"gxlog","func":"Test","msg":"testing",` +
+				`"contexts":[{"k1":"v1"},{"\"k2\"":"v2"}]}`,
+		},
+		{
+			Config: json.Config{
+				Omit:         json.File | json.Line | json.Pkg | json.Func,
+				OmitEmpty:    json.Aux,
+				Keys:         json.Keys{Time: "ts", Level: "severity", Msg: "message"},
+				TimeLayout:   json.EpochMillis,
+				LevelStyle:   json.LevelFull,
+				ContextStyle: json.ContextObject,
+			},
+			Expect: `{"ts":1533107527235,"severity":"INFO","message":"testing",` +
+				`"contexts":{"k1":"v1","\"k2\"":"v2"}}`,
+		},
+		{
+			Config: json.Config{
+				Omit:         json.File | json.Line | json.Pkg | json.Func | json.Prefix,
+				TimeLayout:   json.EpochNanos,
+				LevelStyle:   json.LevelNumeric,
+				ContextStyle: json.ContextFlat,
+			},
+			Expect: `{"time":1533107527235605270,"level":3,"msg":"testing",` +
+				`"k1":"v1","\"k2\"":"v2","marked":false}`,
+		},
+		{
+			Config: json.Config{
+				Omit:         json.Time | json.Level | json.Msg | json.Mark | json.File,
+				OmitEmpty:    json.Prefix,
+				TimeLayout:   "2006-01-02T15:04:05.000000Z07:00",
+				ContextStyle: json.ContextFlat,
+				FuncSegs:     1,
+			},
+			Expect: `{"line":64,"pkg":"github.com/fufuok/gxlog","func":"Test"}`,
+		},
+	}
+	for i, testCase := range testCases {
+		record := tmplRecord
+		if i == 3 {
+			record.Aux.Contexts = nil
+		}
+		output := formatString(t, json.New(testCase.Config), &record)
+		if output != testCase.Expect {
+			t.Errorf("TestFormat:\noutput: %q\nexpect: %q", output, testCase.Expect)
+		}
+	}
+}
+
+func TestTogglePooling(t *testing.T) {
+	formatter := json.New(json.NewConfig())
+	setPooling := func(pooling bool) {
+		formatter.UpdateConfig(func(config json.Config) json.Config {
+			config.Pooling = pooling
+			return config
+		})
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		record := tmplRecord
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			formatter.Release(formatter.Format(&record))
+		}
+	}()
+
+	record := tmplRecord
+	for i := 0; i < 1000; i++ {
+		setPooling(false)
+		record.Msg = strconv.Itoa(i)
+		bs := formatter.Format(&record)
+		expect := string(bs)
+		setPooling(true)
+		// the bs must NOT be put back to the pool to be reused
+		formatter.Release(bs)
+		other := tmplRecord
+		formatter.Release(formatter.Format(&other))
+		if string(bs) != expect {
+			t.Fatalf("TestTogglePooling:\noutput: %q\nexpect: %q", bs, expect)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func BenchmarkFormat(b *testing.B) {
+	formatter := json.New(json.Config{Pooling: true})
+	record := tmplRecord
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			formatter.Release(formatter.Format(&record))
+		}
+	})
+}
+
+func formatString(t *testing.T, formatter *json.Formatter, record *iface.Record) string {
+	bs := formatter.Format(record)
+	if !stdjson.Valid(bs) {
+		t.Errorf("formatString: invalid json: %s", bs)
+	}
+	return string(bs[:len(bs)-1])
+}
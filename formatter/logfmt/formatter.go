@@ -0,0 +1,214 @@
+// Package logfmt implements a logfmt formatter which implements the Formatter.
+// It formats a Record as a line of key=value pairs, e.g.
+//
+//	time=2018-08-01T07:12:07.235605+08:00 level=info file=main.go line=10 pkg=main func=main msg="hello world" reqid=abc
+//
+// The contexts of a Record follow the fields of the Record as pairs of their
+// own. A value is quoted if it is empty or has spaces, '=', '"' or control
+// characters. The characters of a context key other than printable ones
+// except for spaces, '=' and '"' are replaced with '_'.
+package logfmt
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fufuok/gxlog/formatter/internal/util"
+	"github.com/fufuok/gxlog/iface"
+)
+
+const timeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+var levelNames = []string{
+	iface.Trace: "trace",
+	iface.Debug: "debug",
+	iface.Info:  "info",
+	iface.Warn:  "warn",
+	iface.Error: "error",
+	iface.Fatal: "fatal",
+}
+
+// A Formatter implements the interface iface.Formatter and iface.Releaser.
+//
+// All methods of a Formatter are concurrency safe. Logs are formatted with the
+// current Config of the Formatter without locking, and SetConfig and
+// UpdateConfig replace the Config atomically.
+// A Formatter MUST be created with New.
+type Formatter struct {
+	config atomic.Value // *Config
+
+	lock sync.Mutex // serializes UpdateConfig
+}
+
+// New creates a new Formatter with the config.
+func New(config Config) *Formatter {
+	config.setDefaults()
+	formatter := &Formatter{}
+	formatter.config.Store(&config)
+	return formatter
+}
+
+// Format implements the interface Formatter. It formats a Record.
+// If Config.Pooling is true, the returned bytes come from a pool and they
+// should be released with Release after they are written.
+func (formatter *Formatter) Format(record *iface.Record) []byte {
+	config := formatter.load()
+	var buf []byte
+	if config.Pooling {
+		buf = util.GetBuffer(config.MinBufSize)
+	} else {
+		buf = make([]byte, 0, config.MinBufSize)
+	}
+	if config.keep(Time, record.Time.IsZero()) {
+		buf = appendKey(buf, "time")
+		buf = record.Time.AppendFormat(buf, timeLayout)
+	}
+	if config.keep(Level, record.Level == 0) {
+		buf = appendKey(buf, "level")
+		buf = append(buf, levelName(record.Level)...)
+	}
+	if config.keep(File, record.File == "") {
+		file := util.LastSegments(record.File, config.FileSegs, '/')
+		buf = appendStrField(buf, "file", file)
+	}
+	if config.keep(Line, record.Line == 0) {
+		buf = appendKey(buf, "line")
+		buf = strconv.AppendInt(buf, int64(record.Line), 10)
+	}
+	if config.keep(Pkg, record.Pkg == "") {
+		pkg := util.LastSegments(record.Pkg, config.PkgSegs, '/')
+		buf = appendStrField(buf, "pkg", pkg)
+	}
+	if config.keep(Func, record.Func == "") {
+		fn := util.LastSegments(record.Func, config.FuncSegs, '.')
+		buf = appendStrField(buf, "func", fn)
+	}
+	if config.keep(Msg, record.Msg == "") {
+		buf = appendStrField(buf, "msg", record.Msg)
+	}
+	aux := &record.Aux
+	if config.keep(Prefix, aux.Prefix == "") {
+		buf = appendStrField(buf, "prefix", aux.Prefix)
+	}
+	if config.keep(Mark, !aux.Marked) {
+		buf = appendKey(buf, "marked")
+		buf = strconv.AppendBool(buf, aux.Marked)
+	}
+	if config.Omit&Context == 0 {
+		for _, context := range aux.Contexts {
+			buf = appendContext(buf, context.Key, context.Value)
+		}
+	}
+	buf = append(buf, '\n')
+	if !config.Pooling {
+		return util.Unpooled(buf)
+	}
+	return buf
+}
+
+// Release implements the interface Releaser. It puts the bs returned by Format
+// back to the pool if Config.Pooling was true when the bs was formatted,
+// otherwise it does nothing. The bs must NOT be used after it is released.
+func (formatter *Formatter) Release(bs []byte) {
+	util.ReleaseBuffer(bs)
+}
+
+// Config returns the Config of the Formatter.
+func (formatter *Formatter) Config() Config {
+	return *formatter.load()
+}
+
+// SetConfig sets the config to the Formatter.
+func (formatter *Formatter) SetConfig(config Config) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	config.setDefaults()
+	formatter.config.Store(&config)
+}
+
+// UpdateConfig calls the fn with the Config of the Formatter, and then sets the
+// returned Config to the Formatter. The fn must NOT be nil.
+//
+// Do NOT call any method of the Formatter or the Logger within the fn,
+// or it may deadlock.
+func (formatter *Formatter) UpdateConfig(fn func(Config) Config) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	config := fn(*formatter.load())
+	config.setDefaults()
+	formatter.config.Store(&config)
+}
+
+func (formatter *Formatter) load() *Config {
+	return formatter.config.Load().(*Config)
+}
+
+func (config *Config) keep(field OmitBits, empty bool) bool {
+	if config.Omit&field != 0 {
+		return false
+	}
+	return !empty || config.OmitEmpty&field == 0
+}
+
+func levelName(level iface.Level) string {
+	if level < iface.Trace || level > iface.Fatal {
+		return "unknown"
+	}
+	return levelNames[level]
+}
+
+func appendKey(buf []byte, key string) []byte {
+	if len(buf) > 0 {
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, key...)
+	return append(buf, '=')
+}
+
+func appendStrField(buf []byte, key, value string) []byte {
+	buf = appendKey(buf, key)
+	return appendValue(buf, value)
+}
+
+func appendContext(buf []byte, key, value string) []byte {
+	if len(buf) > 0 {
+		buf = append(buf, ' ')
+	}
+	if key == "" {
+		buf = append(buf, '_')
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	buf = append(buf, '=')
+	return appendValue(buf, value)
+}
+
+func appendValue(buf []byte, value string) []byte {
+	if !needsQuoting(value) {
+		return append(buf, value...)
+	}
+	buf = append(buf, '"')
+	buf = util.EscapeJSON(buf, value)
+	return append(buf, '"')
+}
+
+func needsQuoting(value string) bool {
+	if value == "" {
+		return true
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package logfmt_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/formatter/logfmt"
+	"github.com/fufuok/gxlog/iface"
+)
+
+var tmplRecord = iface.Record{
+	Time:  time.Date(2018, 8, 1, 7, 12, 7, 235605270, time.UTC),
+	Level: iface.Info,
+	File:  "/home/test/src/github.com/fufuok/gxlog/logger.go",
+	Line:  64,
+	Pkg:   "github.com/fufuok/gxlog",
+	Func:  "Test",
+	Msg:   `say "hi"` + "\n",
+	Aux: iface.Auxiliary{
+		Contexts: []iface.Context{
+			{Key: "k1", Value: "v1"},
+			{Key: "bad key=", Value: ""},
+			{Key: "path", Value: `C:\tmp`},
+		},
+	},
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		Config logfmt.Config
+		Expect string
+	}{
+		{
+			Config: logfmt.NewConfig(),
+			Expect: `time=2018-08-01T07:12:07.235605Z level=info file=logger.go line=64 ` +
+				`pkg=gxlog func=Test msg="say \"hi\"\n" k1=v1 bad_key_="" path="C:\\tmp"` + "\n",
+		},
+		{
+			Config: logfmt.Config{
+				Omit:      logfmt.Time | logfmt.File | logfmt.Line | logfmt.Context,
+				OmitEmpty: logfmt.Prefix,
+				PkgSegs:   2,
+			},
+			Expect: `level=info pkg=fufuok/gxlog func=Test msg="say \"hi\"\n" marked=false` + "\n",
+		},
+	}
+	for _, testCase := range testCases {
+		formatter := logfmt.New(testCase.Config)
+		output := string(formatter.Format(&tmplRecord))
+		if output != testCase.Expect {
+			t.Errorf("TestFormat:\noutput: %q\nexpect: %q", output, testCase.Expect)
+		}
+	}
+
+	record := iface.Record{Level: iface.Warn, Msg: "done"}
+	record.Aux.Prefix = "**** "
+	record.Aux.Marked = true
+	formatter := logfmt.New(logfmt.Config{OmitEmpty: logfmt.Time | logfmt.File |
+		logfmt.Line | logfmt.Pkg | logfmt.Func})
+	expect := `level=warn msg=done prefix="**** " marked=true` + "\n"
+	if output := string(formatter.Format(&record)); output != expect {
+		t.Errorf("TestFormat:\noutput: %q\nexpect: %q", output, expect)
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	config := logfmt.NewConfig()
+	config.Pooling = true
+	formatter := logfmt.New(config)
+	record := tmplRecord
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			formatter.Release(formatter.Format(&record))
+		}
+	})
+}
@@ -0,0 +1,218 @@
+// Package otel implements an otel formatter which implements the Formatter.
+// It formats a Record as a JSON object of the OpenTelemetry logs data model as
+// follows:
+//
+//	Timestamp       the Time in nanoseconds since the Unix epoch, as a string
+//	                to keep the precision in JavaScript
+//	SeverityNumber  the severity number of the Level, e.g. 9 for Info
+//	SeverityText    the level name, e.g. "INFO"
+//	Body            the Msg
+//	Attributes      code.filepath, code.lineno, code.function, code.namespace,
+//	                gxlog.prefix, gxlog.marked and the other contexts
+//	TraceId, SpanId the contexts of the trace key and the span key
+//	Resource        the resource of the config if it is not empty
+//
+// Attributes of empty values are omitted.
+package otel
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fufuok/gxlog/formatter/internal/util"
+	"github.com/fufuok/gxlog/iface"
+)
+
+var severityNumbers = []int{
+	iface.Trace: 1,
+	iface.Debug: 5,
+	iface.Info:  9,
+	iface.Warn:  13,
+	iface.Error: 17,
+	iface.Fatal: 21,
+}
+
+var severityTexts = []string{
+	iface.Trace: "TRACE",
+	iface.Debug: "DEBUG",
+	iface.Info:  "INFO",
+	iface.Warn:  "WARN",
+	iface.Error: "ERROR",
+	iface.Fatal: "FATAL",
+}
+
+// A Formatter implements the interface iface.Formatter and iface.Releaser.
+//
+// All methods of a Formatter are concurrency safe. Logs are formatted with the
+// current Config of the Formatter without locking, and SetConfig and
+// UpdateConfig replace the Config atomically.
+// A Formatter MUST be created with New.
+type Formatter struct {
+	snapshot atomic.Value // *snapshot
+
+	lock sync.Mutex // serializes UpdateConfig
+}
+
+// A snapshot is the immutable state of a Formatter.
+type snapshot struct {
+	config   Config
+	resource []byte
+}
+
+// New creates a new Formatter with the config.
+func New(config Config) *Formatter {
+	formatter := &Formatter{}
+	formatter.setConfig(config)
+	return formatter
+}
+
+// Format implements the interface Formatter. It formats a Record.
+// If Config.Pooling is true, the returned bytes come from a pool and they
+// should be released with Release after they are written.
+func (formatter *Formatter) Format(record *iface.Record) []byte {
+	snap := formatter.load()
+	var buf []byte
+	if snap.config.Pooling {
+		buf = util.GetBuffer(snap.config.MinBufSize)
+	} else {
+		buf = make([]byte, 0, snap.config.MinBufSize)
+	}
+	buf = append(buf, `{"Timestamp":"`...)
+	buf = strconv.AppendInt(buf, record.Time.UnixNano(), 10)
+	buf = append(buf, `"`...)
+	if record.Level >= iface.Trace && record.Level <= iface.Fatal {
+		buf = append(buf, `,"SeverityNumber":`...)
+		buf = strconv.AppendInt(buf, int64(severityNumbers[record.Level]), 10)
+		buf = appendStrField(buf, ",", "SeverityText", severityTexts[record.Level])
+	}
+	buf = appendStrField(buf, ",", "Body", record.Msg)
+	buf = snap.formatAttributes(buf, record)
+	buf = append(buf, snap.resource...)
+	buf = append(buf, "}\n"...)
+	if !snap.config.Pooling {
+		return util.Unpooled(buf)
+	}
+	return buf
+}
+
+// Release implements the interface Releaser. It puts the bs returned by Format
+// back to the pool if Config.Pooling was true when the bs was formatted,
+// otherwise it does nothing. The bs must NOT be used after it is released.
+func (formatter *Formatter) Release(bs []byte) {
+	util.ReleaseBuffer(bs)
+}
+
+// Config returns the Config of the Formatter.
+func (formatter *Formatter) Config() Config {
+	return formatter.load().config
+}
+
+// SetConfig sets the config to the Formatter.
+func (formatter *Formatter) SetConfig(config Config) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	formatter.setConfig(config)
+}
+
+// UpdateConfig calls the fn with the Config of the Formatter, and then sets the
+// returned Config to the Formatter. The fn must NOT be nil.
+//
+// Do NOT call any method of the Formatter or the Logger within the fn,
+// or it may deadlock.
+func (formatter *Formatter) UpdateConfig(fn func(Config) Config) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	formatter.setConfig(fn(formatter.load().config))
+}
+
+func (formatter *Formatter) load() *snapshot {
+	return formatter.snapshot.Load().(*snapshot)
+}
+
+func (formatter *Formatter) setConfig(config Config) {
+	config.setDefaults()
+	formatter.snapshot.Store(&snapshot{
+		config:   config,
+		resource: formatResource(config.Resource),
+	})
+}
+
+func formatResource(attrs map[string]string) []byte {
+	if len(attrs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	resource := []byte(`,"Resource":{`)
+	sep := ""
+	for _, key := range keys {
+		resource = appendStrField(resource, sep, key, attrs[key])
+		sep = ","
+	}
+	return append(resource, '}')
+}
+
+func (snap *snapshot) formatAttributes(buf []byte, record *iface.Record) []byte {
+	buf = append(buf, `,"Attributes":{`...)
+	sep := ""
+	if record.File != "" {
+		file := util.LastSegments(record.File, snap.config.FileSegs, '/')
+		buf = appendStrField(buf, sep, "code.filepath", file)
+		buf = append(buf, `,"code.lineno":`...)
+		buf = strconv.AppendInt(buf, int64(record.Line), 10)
+		sep = ","
+	}
+	if record.Func != "" {
+		buf = appendStrField(buf, sep, "code.function", record.Func)
+		sep = ","
+	}
+	if record.Pkg != "" {
+		buf = appendStrField(buf, sep, "code.namespace", record.Pkg)
+		sep = ","
+	}
+	if record.Aux.Prefix != "" {
+		buf = appendStrField(buf, sep, "gxlog.prefix", record.Aux.Prefix)
+		sep = ","
+	}
+	if record.Aux.Marked {
+		buf = append(buf, sep...)
+		buf = append(buf, `"gxlog.marked":true`...)
+		sep = ","
+	}
+	var traceID, spanID string
+	for _, context := range record.Aux.Contexts {
+		switch context.Key {
+		case snap.config.TraceKey:
+			traceID = context.Value
+		case snap.config.SpanKey:
+			spanID = context.Value
+		default:
+			buf = appendStrField(buf, sep, context.Key, context.Value)
+			sep = ","
+		}
+	}
+	buf = append(buf, '}')
+	if traceID != "" {
+		buf = appendStrField(buf, ",", "TraceId", traceID)
+	}
+	if spanID != "" {
+		buf = appendStrField(buf, ",", "SpanId", spanID)
+	}
+	return buf
+}
+
+func appendStrField(buf []byte, sep, key, value string) []byte {
+	buf = append(buf, sep...)
+	buf = append(buf, '"')
+	buf = util.EscapeJSON(buf, key)
+	buf = append(buf, `":"`...)
+	buf = util.EscapeJSON(buf, value)
+	return append(buf, '"')
+}
@@ -0,0 +1,71 @@
+package otel_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/formatter/otel"
+	"github.com/fufuok/gxlog/iface"
+)
+
+var tmplRecord = iface.Record{
+	Time:  time.Unix(1533107527, 235605270),
+	Level: iface.Warn,
+	File:  "/home/test/src/github.com/fufuok/gxlog/logger.go",
+	Line:  64,
+	Pkg:   "github.com/fufuok/gxlog",
+	Func:  "Test",
+	Msg:   `say "hi"`,
+	Aux: iface.Auxiliary{
+		Prefix: "**** ",
+		Contexts: []iface.Context{
+			{Key: "trace_id", Value: "4bf92f3577b34da6a3ce929d0e0e4736"},
+			{Key: "span_id", Value: "00f067aa0ba902b7"},
+			{Key: "user.id", Value: "42"},
+		},
+		Marked: true,
+	},
+}
+
+func TestFormat(t *testing.T) {
+	formatter := otel.New(otel.Config{
+		Resource: map[string]string{"service.name": "app", "host.name": "host"},
+		FileSegs: 1,
+	})
+	expect := `{"Timestamp":"1533107527235605270","SeverityNumber":13,` +
+		`"SeverityText":"WARN","Body":"say \"hi\"","Attributes":{` +
+		`"code.filepath":"logger.go","code.lineno":64,"code.function":"Test",` +
+		`"code.namespace":"github.com/fufuok/gxlog","gxlog.prefix":"**** ",` +
+		`"gxlog.marked":true,"user.id":"42"},` +
+		`"TraceId":"4bf92f3577b34da6a3ce929d0e0e4736","SpanId":"00f067aa0ba902b7",` +
+		`"Resource":{"host.name":"host","service.name":"app"}}` + "\n"
+	output := formatter.Format(&tmplRecord)
+	if string(output) != expect {
+		t.Errorf("TestFormat:\noutput: %q\nexpect: %q", output, expect)
+	}
+
+	formatter.SetConfig(otel.Config{})
+	record := iface.Record{Time: tmplRecord.Time, Level: iface.Info, Msg: "done"}
+	output = formatter.Format(&record)
+	expect = `{"Timestamp":"1533107527235605270","SeverityNumber":9,` +
+		`"SeverityText":"INFO","Body":"done","Attributes":{}}` + "\n"
+	if string(output) != expect {
+		t.Errorf("TestFormat:\noutput: %q\nexpect: %q", output, expect)
+	}
+	if !json.Valid(output) {
+		t.Errorf("TestFormat: invalid json: %s", output)
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	formatter := otel.New(otel.Config{Pooling: true})
+	record := tmplRecord
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			formatter.Release(formatter.Format(&record))
+		}
+	})
+}
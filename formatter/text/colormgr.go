@@ -1,8 +1,7 @@
 package text
 
 import (
-	"fmt"
-
+	"github.com/fufuok/gxlog/formatter/internal/util"
 	"github.com/fufuok/gxlog/iface"
 )
 
@@ -33,36 +32,84 @@ const (
 	BrightWhite
 )
 
-const escSeqFmt = "\033[%dm"
+var defaultColors = []Color{
+	iface.Trace: Green,
+	iface.Debug: Green,
+	iface.Info:  Blue,
+	iface.Warn:  Yellow,
+	iface.Error: Red,
+	iface.Fatal: Red,
+}
+
+// Color256 returns the Color of the index in the 256-color palette.
+func Color256(index uint8) Color {
+	return Color(util.Color256Flag | int(index))
+}
+
+// TrueColor returns the 24-bit Color of the red, green and blue components.
+func TrueColor(red, green, blue uint8) Color {
+	return Color(util.TrueColorFlag | int(red)<<16 | int(green)<<8 | int(blue))
+}
+
+// DefaultColor returns the default color of the level. The color of Trace and
+// Debug is Green, the color of Info is Blue, the color of Warn is Yellow, the
+// color of Error and Fatal is Red. It returns 0 for an unknown level.
+func DefaultColor(level iface.Level) Color {
+	if level < iface.Trace || level > iface.Fatal {
+		return 0
+	}
+	return defaultColors[level]
+}
+
+// code returns the SGR parameters of the color as a foreground color, or as a
+// background color if bg is true.
+func (color Color) code(bg bool) string {
+	return util.ColorCode(int(color), bg)
+}
+
+// Sequence returns the ANSI escape sequence that sets the color as the
+// foreground color. A Color of 0 resets all colors and styles.
+func (color Color) Sequence() string {
+	return util.SGR(color.code(false))
+}
 
 type colorMgr struct {
 	colors      []Color
 	markedColor Color
 
-	colorSeqs [][]byte
-	markedSeq []byte
-	resetSeq  []byte
+	colorSeqs  [][]byte
+	markedSeq  []byte
+	resetSeq   []byte
+	colorCodes []string
+	markedCode string
 }
 
 func newColorMgr() *colorMgr {
-	colors := []Color{
-		iface.Trace: Green,
-		iface.Debug: Green,
-		iface.Info:  Blue,
-		iface.Warn:  Yellow,
-		iface.Error: Red,
-		iface.Fatal: Red,
-	}
+	colors := append([]Color(nil), defaultColors...)
 	mgr := &colorMgr{
 		colors:      colors,
 		markedColor: Magenta,
-		colorSeqs:   initColorSeqs(colors),
+		colorSeqs:   make([][]byte, len(colors)),
 		markedSeq:   makeSeq(Magenta),
 		resetSeq:    makeSeq(0),
+		colorCodes:  make([]string, len(colors)),
+		markedCode:  Magenta.code(false),
+	}
+	for level, color := range colors {
+		mgr.SetColor(iface.Level(level), color)
 	}
 	return mgr
 }
 
+// Clone returns a deep copy of the mgr.
+func (mgr *colorMgr) Clone() *colorMgr {
+	clone := *mgr
+	clone.colors = append([]Color(nil), mgr.colors...)
+	clone.colorSeqs = append([][]byte(nil), mgr.colorSeqs...)
+	clone.colorCodes = append([]string(nil), mgr.colorCodes...)
+	return &clone
+}
+
 func (mgr *colorMgr) Color(level iface.Level) Color {
 	return mgr.colors[level]
 }
@@ -70,6 +117,7 @@ func (mgr *colorMgr) Color(level iface.Level) Color {
 func (mgr *colorMgr) SetColor(level iface.Level, color Color) {
 	mgr.colors[level] = color
 	mgr.colorSeqs[level] = makeSeq(color)
+	mgr.colorCodes[level] = color.code(false)
 }
 
 func (mgr *colorMgr) MapColors(colorMap map[iface.Level]Color) {
@@ -85,6 +133,17 @@ func (mgr *colorMgr) MarkedColor() Color {
 func (mgr *colorMgr) SetMarkedColor(color Color) {
 	mgr.markedColor = color
 	mgr.markedSeq = makeSeq(color)
+	mgr.markedCode = color.code(false)
+}
+
+// ColorCode returns the SGR parameters of the color of the level, or of the
+// marked color if marked is true. The parameters are cached when the colors
+// are set.
+func (mgr *colorMgr) ColorCode(level iface.Level, marked bool) string {
+	if marked {
+		return mgr.markedCode
+	}
+	return mgr.colorCodes[level]
 }
 
 func (mgr *colorMgr) ColorEars(level iface.Level) ([]byte, []byte) {
@@ -95,14 +154,6 @@ func (mgr *colorMgr) MarkedColorEars() ([]byte, []byte) {
 	return mgr.markedSeq, mgr.resetSeq
 }
 
-func initColorSeqs(colors []Color) [][]byte {
-	colorSeqs := make([][]byte, len(colors))
-	for i := range colors {
-		colorSeqs[i] = makeSeq(colors[i])
-	}
-	return colorSeqs
-}
-
 func makeSeq(color Color) []byte {
-	return []byte(fmt.Sprintf(escSeqFmt, color))
+	return []byte(color.Sequence())
 }
@@ -14,12 +14,43 @@ const (
 		"[{{context}}] {{msg}}\n"
 )
 
+// The ColorMode defines the type of colorization of a text formatter.
+type ColorMode int
+
+// All available color modes here.
+const (
+	// The whole line of a log is colored with the color of its level, or the
+	// marked color if it is marked. Styles of elements are ignored.
+	LineColor ColorMode = iota
+	// Only the elements with styles are colored, e.g. {{level@level,bold}}.
+	ElementColor
+)
+
+// The MultiLineMode defines how the continuation lines of a multi-line message
+// are formatted, e.g. the stack appended when the track level is reached.
+type MultiLineMode int
+
+// All available multi-line modes here.
+const (
+	// Continuation lines are output as they are.
+	MultiLineRaw MultiLineMode = iota
+	// Each continuation line is prefixed with the LinePrefix.
+	MultiLinePrefix
+	// Each continuation line is prefixed with the header of the log, which is
+	// the output before the msg element.
+	MultiLineHeader
+	// Each newline and carriage return is escaped as "\n" and "\r", so that
+	// a log with a header ending with a newline is always one line.
+	MultiLineEscape
+)
+
 // A Config is used to configure a text formatter.
 type Config struct {
 	// Header is the format specifier of a text formatter.
 	// It is used to specify which and how the fields of a Record to be formatted.
-	// The pattern of a field specifier is {{<name>[:property][%fmtstr]}}.
-	// e.g. {{level:char}}, {{line%05d}}, {{pkg:1}}, {{context:list%40s}}
+	// The pattern of a field specifier is {{<name>[:property][%fmtstr][@style]}}.
+	// e.g. {{level:char}}, {{line%05d}}, {{pkg:1}}, {{context:list%40s}},
+	// {{level:char@level,bold}}, {{time@dim}}, {{file:1@cyan}}
 	// All fields have support for the fmtstr. If the fmtstr is NOT the default
 	// one of a field, it will be passed to fmt.Sprintf to format the field and
 	// this affects the performance a little.
@@ -36,21 +67,93 @@ type Config struct {
 	//   pkg     | <lastSegs>               | 0         %s | 0, 1, 2, ...
 	//   func    | <lastSegs>               | 0         %s | 0, 1, 2, ...
 	//   prefix  |                          |           %s |
-	//   context | <pair|list>              | "pair"    %s | "pair", "list"
+	//   context | <pair|list|kv>           | "pair"    %s | "pair", "list", "kv"
+	//   ctx     | <keys>[|default][:style] |           %s | "reqid", "reqid|-"
+	//           |                          |              | "*-reqid,user:kv"
 	//   msg     |                          |           %s |
+	//   host    |                          |           %s |
+	//   pid     |                          |           %d |
+	//   gid     |                          |           %d |
+	//   elapsed | <start|prev>[.<unit>]    | "start.ms"%s | "prev.us", "start.s"
+	//   app     |                          |           %s |
+	//   env     | <NAME>                   | ""        %s | "HOME", "USER"
+	//   mark    | <text>                   | "MARKED"  %s | "M", "!!!"
+	// The ctx selects contexts by the keys, which is a key, keys separated by
+	// commas, "*" for all or "*-" followed by keys for all except for them.
+	// The value of the context is output for a key, otherwise the selected
+	// contexts are output in the style of the context. The default is output
+	// if no context is selected.
+	// The host is the hostname, the app is the base name of the executable and
+	// the env is the value of an environment variable. They are evaluated when
+	// the header is set. The gid is the id of the goroutine that emits the log, a
+	// Logger gets it only if the header contains the gid element.
+	// The elapsed is the time elapsed since the process starts or since the
+	// previous log formatted with the header, rounded to the unit, which is one
+	// of s, ms, us and ns.
+	// The mark is the text if the log is marked, otherwise it is empty.
+	// A conditional element is in the pattern of {{?<name>[:property]:<text>}},
+	// e.g. {{?context: [%s]}}, {{?ctx:reqid: reqid=%s}}, {{?mark: [MARKED]}}.
+	// The text is output with the fmtspec in it replaced by the element only if
+	// the element is not empty, otherwise nothing is output. The last colon
+	// before the fmtspec ends the property, so the text before the fmtspec must
+	// NOT contain any colon. If there is no fmtspec in the text, the element is
+	// not output but only the text. A conditional element has no style.
+	// The style takes effect only if ColorMode is ElementColor. It is a list of
+	// tokens separated by commas. A token is "level" for the color of the level
+	// (or the marked color if the log is marked), a color for the foreground,
+	// "bg-" followed by a color for the background, or one of bold, dim,
+	// italic, underline, blink and reverse. A color is a basic color name
+	// (black, red, green, yellow, blue, magenta, cyan, white), "bright-"
+	// followed by a basic color name, an index of the 256-color palette (e.g.
+	// 208) or a 24-bit color in hex (e.g. #ff8800).
+	// Custom elements can be registered with RegisterElement globally or with
+	// Formatter.RegisterElement per Formatter.
 	// If Header is not specified, FullHeader is used.
 	Header string
+	// Strict specifies whether the Formatter is in strict mode. In strict mode,
+	// Formatter.SetHeader returns an error if there is an unknown element or
+	// an element with an invalid property in the header, and New panics if the
+	// Header is invalid. Otherwise, such elements are ignored.
+	Strict bool
+	// Pooling specifies whether the bytes returned by Formatter.Format come
+	// from a pool. See iface.Releaser for when they are put back and what it
+	// requires of writers.
+	Pooling bool
 	// MinBufSize is the initial size of the internal buf of a formatter.
 	// MinBufSize must NOT be negative. If it is not specified, 256 is used.
 	MinBufSize int
 	// ColorMap is used to remap the color of each level.
-	// By default, the color of Trace, Debug and Info is Green, the color of Warn
-	// is Yellow, the color of Error and Fatal is Red. The color of a marked log
-	// is Magenta despite of its level.
+	// By default, the color of a level is DefaultColor(level). The color of a
+	// marked log is Magenta despite of its level.
 	// The color of a level is left to be unchanged if it is not in the map.
 	ColorMap map[iface.Level]Color
-	// Coloring specifies whether colorization is enabled.
+	// Coloring specifies whether colorization is enabled. It is ignored if
+	// ColorAuto is true.
 	Coloring bool
+	// ColorAuto specifies that colorization is decided by where logs are
+	// written to. A formatter returned by Formatter.ForWriter(w) colors logs
+	// only if ColorSupported(w) returns true, and Formatter.Format colors logs
+	// only if ColorSupported(os.Stderr) returns true. Both are evaluated once,
+	// when ForWriter is called and when ColorAuto is set respectively.
+	ColorAuto bool
+	// ColorMode specifies how logs are colored if Coloring is true.
+	// If it is not specified, LineColor is used.
+	ColorMode ColorMode
+	// MultiLine specifies how the continuation lines of a message are output.
+	// If it is not specified, MultiLineRaw is used.
+	MultiLine MultiLineMode
+	// LinePrefix is the prefix of continuation lines in MultiLinePrefix mode,
+	// e.g. "    " or "  | ". If it is not specified, "\t" is used.
+	LinePrefix string
+	// MaxMsgSize is the max size in bytes of a message. A longer message is
+	// truncated at a rune boundary and the Ellipsis is appended. A message is
+	// truncated before the fmtspec of the msg element is applied, so padding
+	// does NOT count toward MaxMsgSize. MaxMsgSize must NOT be negative. If it
+	// is not specified, messages are never truncated.
+	MaxMsgSize int
+	// Ellipsis is appended to a truncated message.
+	// If it is not specified, "..." is used.
+	Ellipsis string
 }
 
 func (config *Config) setDefaults() {
@@ -60,11 +163,18 @@ func (config *Config) setDefaults() {
 	if config.MinBufSize == 0 {
 		config.MinBufSize = 256
 	}
+	if config.LinePrefix == "" {
+		config.LinePrefix = "\t"
+	}
+	if config.Ellipsis == "" {
+		config.Ellipsis = "..."
+	}
 }
 
+// NewConfig returns a Config for the console with ColorAuto enabled.
 func NewConfig() Config {
 	return Config{
-		Coloring: true,
+		ColorAuto: true,
 		Header: "{{time:time.ms}} {{level:char}} {{file:1}}:{{line}} " +
 			"{{pkg:1}}.{{func}} {{prefix}}[{{context}}] {{msg}}\n",
 	}
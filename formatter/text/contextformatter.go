@@ -1,65 +1,68 @@
 package text
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/fufuok/gxlog/iface"
 )
 
+// A contextStyle is the style in which contexts are formatted.
+type contextStyle struct {
+	open  string
+	kvSep string
+	close string
+	sep   string
+}
+
+var (
+	pairStyle = &contextStyle{open: "(", kvSep: ": ", close: ")", sep: " "}
+	listStyle = &contextStyle{kvSep: ": ", sep: ", "}
+	kvStyle   = &contextStyle{kvSep: "=", sep: " "}
+)
+
+// AppendContext appends the context to the buf. The separator is prepended
+// unless the context is the first one.
+func (style *contextStyle) AppendContext(buf []byte, ctx *iface.Context, first bool) []byte {
+	if !first {
+		buf = append(buf, style.sep...)
+	}
+	buf = append(buf, style.open...)
+	buf = append(buf, ctx.Key...)
+	buf = append(buf, style.kvSep...)
+	buf = append(buf, ctx.Value...)
+	return append(buf, style.close...)
+}
+
 type contextFormatter struct {
-	formatter func([]byte, []iface.Context) []byte
-	fmtspec   string
-	buf       []byte
+	style *contextStyle
+	spec  fmtSpec
 }
 
-func newContextFormatter(property, fmtspec string) elementFormatter {
+func newContextFormatter(property, fmtspec string) ElementFormatter {
 	if fmtspec == "" {
 		fmtspec = "%s"
 	}
 	return &contextFormatter{
-		formatter: selectFormatter(property),
-		fmtspec:   fmtspec,
+		style: selectStyle(property),
+		spec:  newFmtSpec(fmtspec),
 	}
 }
 
 func (formatter *contextFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
-	if formatter.fmtspec == "%s" {
-		return formatter.formatter(buf, record.Aux.Contexts)
-	}
-	formatter.buf = formatter.buf[:0]
-	formatter.buf = formatter.formatter(formatter.buf, record.Aux.Contexts)
-	return append(buf, fmt.Sprintf(formatter.fmtspec, formatter.buf)...)
-}
-
-func selectFormatter(property string) func([]byte, []iface.Context) []byte {
-	if strings.ToLower(property) == "list" {
-		return formatList
-	}
-	return formatPair
-}
-
-func formatPair(buf []byte, contexts []iface.Context) []byte {
-	left := "("
-	for _, ctx := range contexts {
-		buf = append(buf, left...)
-		buf = append(buf, ctx.Key...)
-		buf = append(buf, ": "...)
-		buf = append(buf, ctx.Value...)
-		buf = append(buf, ')')
-		left = " ("
+	begin := len(buf)
+	contexts := record.Aux.Contexts
+	for i := range contexts {
+		buf = formatter.style.AppendContext(buf, &contexts[i], i == 0)
 	}
-	return buf
+	return formatter.spec.FormatBytes(buf, begin)
 }
 
-func formatList(buf []byte, contexts []iface.Context) []byte {
-	begin := ""
-	for _, ctx := range contexts {
-		buf = append(buf, begin...)
-		buf = append(buf, ctx.Key...)
-		buf = append(buf, ": "...)
-		buf = append(buf, ctx.Value...)
-		begin = ", "
+func selectStyle(property string) *contextStyle {
+	switch strings.ToLower(property) {
+	case "list":
+		return listStyle
+	case "kv":
+		return kvStyle
 	}
-	return buf
+	return pairStyle
 }
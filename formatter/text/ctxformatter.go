@@ -0,0 +1,107 @@
+package text
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+// ctxFormatter formats the selected contexts. The property is in the form of
+// <keys>[|default][:style], where the keys is one of the follows:
+//
+//	key          the value of the context with the key
+//	k1,k2        the contexts with any of the keys in the style
+//	*            all the contexts in the style
+//	*-k1,k2      all the contexts except for the ones with any of the keys
+//
+// The default is output if no context is selected.
+type ctxFormatter struct {
+	single    string
+	keys      map[string]bool
+	exclusive bool
+	dflt      string
+	style     *contextStyle
+	spec      fmtSpec
+}
+
+func newCtxFormatter(property, fmtspec string) ElementFormatter {
+	if fmtspec == "" {
+		fmtspec = "%s"
+	}
+	keys, dflt, style := parseCtxProperty(property)
+	formatter := &ctxFormatter{
+		dflt:  dflt,
+		style: selectStyle(style),
+		spec:  newFmtSpec(fmtspec),
+	}
+	switch {
+	case keys == "*":
+		formatter.exclusive = true
+	case strings.HasPrefix(keys, "*-"):
+		formatter.exclusive = true
+		formatter.keys = splitKeys(keys[2:])
+	case strings.Contains(keys, ","):
+		formatter.keys = splitKeys(keys)
+	default:
+		formatter.single = keys
+	}
+	return formatter
+}
+
+func (formatter *ctxFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
+	begin := len(buf)
+	buf = formatter.format(buf, record.Aux.Contexts)
+	if len(buf) == begin {
+		buf = append(buf, formatter.dflt...)
+	}
+	return formatter.spec.FormatBytes(buf, begin)
+}
+
+func (formatter *ctxFormatter) format(buf []byte, contexts []iface.Context) []byte {
+	if formatter.keys == nil && !formatter.exclusive {
+		for i := len(contexts) - 1; i >= 0; i-- {
+			if contexts[i].Key == formatter.single {
+				return append(buf, contexts[i].Value...)
+			}
+		}
+		return buf
+	}
+	first := true
+	for i := range contexts {
+		if formatter.keys[contexts[i].Key] != formatter.exclusive {
+			buf = formatter.style.AppendContext(buf, &contexts[i], first)
+			first = false
+		}
+	}
+	return buf
+}
+
+func parseCtxProperty(property string) (keys, dflt, style string) {
+	keys = property
+	if i := strings.LastIndexByte(keys, ':'); i >= 0 {
+		keys, style = keys[:i], strings.TrimSpace(keys[i+1:])
+	}
+	if i := strings.IndexByte(keys, '|'); i >= 0 {
+		keys, dflt = keys[:i], keys[i+1:]
+	}
+	return strings.TrimSpace(keys), dflt, style
+}
+
+func splitKeys(str string) map[string]bool {
+	keys := make(map[string]bool)
+	for _, key := range strings.Split(str, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+func checkCtxProperty(property string) error {
+	keys, _, style := parseCtxProperty(property)
+	if keys == "" {
+		return errors.New("property must specify the keys")
+	}
+	return checkOptions("pair", "list", "kv")(style)
+}
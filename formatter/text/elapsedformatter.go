@@ -0,0 +1,53 @@
+package text
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+// processStart is approximately the time when the process starts.
+var processStart = time.Now()
+
+type elapsedFormatter struct {
+	prev      int64 // the UnixNano of the previous log, accessed atomically
+	sincePrev bool
+	unit      time.Duration
+	spec      fmtSpec
+}
+
+func newElapsedFormatter(property, fmtspec string) ElementFormatter {
+	if fmtspec == "" {
+		fmtspec = "%s"
+	}
+	sinceType, unitType := getTimeOptions(property)
+	unit := time.Millisecond
+	switch unitType {
+	case "s":
+		unit = time.Second
+	case "us":
+		unit = time.Microsecond
+	case "ns":
+		unit = time.Nanosecond
+	}
+	return &elapsedFormatter{
+		sincePrev: strings.ToLower(sinceType) == "prev",
+		unit:      unit,
+		spec:      newFmtSpec(fmtspec),
+	}
+}
+
+func (formatter *elapsedFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
+	since := processStart
+	if formatter.sincePrev {
+		prev := atomic.SwapInt64(&formatter.prev, record.Time.UnixNano())
+		since = record.Time
+		if prev != 0 {
+			since = time.Unix(0, prev)
+		}
+	}
+	elapsed := record.Time.Sub(since).Round(formatter.unit).String()
+	return formatter.spec.AppendString(buf, elapsed)
+}
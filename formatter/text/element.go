@@ -0,0 +1,148 @@
+package text
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+// An ElementFormatter formats an element of a header, e.g. {{level:char}}.
+// It is called without any lock held since logs are formatted concurrently, so
+// it MUST be concurrency safe.
+//
+// Do NOT call any method of the Formatter or the Logger within FormatElement,
+// or it may deadlock.
+type ElementFormatter interface {
+	FormatElement(buf []byte, record *iface.Record) []byte
+}
+
+// The NewElementFunc type is a function type used to create an ElementFormatter
+// of a custom element with the property and fmtspec of the element. Both the
+// property and fmtspec are trimmed and the fmtspec is empty if it is NOT
+// specified. It returns an error if the property or fmtspec is invalid.
+type NewElementFunc func(property, fmtspec string) (ElementFormatter, error)
+
+var customElements = struct {
+	funcMap map[string]NewElementFunc
+	lock    sync.RWMutex
+}{funcMap: make(map[string]NewElementFunc)}
+
+// RegisterElement registers a custom element with the name for all Formatters.
+// Names are case-insensitive. It takes effect on headers set after the
+// registration. A custom element of a Formatter takes precedence over a
+// global one with the same name. If the fn is nil, the custom element is
+// unregistered.
+// It returns an error if the name is invalid or it is the name of a builtin
+// element.
+func RegisterElement(name string, fn NewElementFunc) error {
+	name, err := checkElementName(name)
+	if err != nil {
+		return fmt.Errorf("formatter/text.RegisterElement: %v", err)
+	}
+	customElements.lock.Lock()
+	defer customElements.lock.Unlock()
+
+	if fn == nil {
+		delete(customElements.funcMap, name)
+	} else {
+		customElements.funcMap[name] = fn
+	}
+	return nil
+}
+
+func lookupElement(name string) NewElementFunc {
+	customElements.lock.RLock()
+	defer customElements.lock.RUnlock()
+
+	return customElements.funcMap[name]
+}
+
+func checkElementName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || strings.ContainsAny(name, ":%{} \t\r\n") {
+		return "", fmt.Errorf("invalid element name %q", name)
+	}
+	if _, ok := newFormatterFuncMap[name]; ok {
+		return "", fmt.Errorf("element %q is builtin", name)
+	}
+	return name, nil
+}
+
+// propertyCheckers checks the property of builtin elements in strict mode.
+// Builtin elements without a checker accept any property.
+var propertyCheckers = map[string]func(property string) error{
+	"time":    checkTimeProperty,
+	"level":   checkOptions("full", "char"),
+	"file":    checkSegments,
+	"pkg":     checkSegments,
+	"func":    checkSegments,
+	"context": checkOptions("pair", "list", "kv"),
+	"ctx":     checkCtxProperty,
+	"elapsed": checkElapsedProperty,
+	"env":     checkEnvProperty,
+}
+
+func checkOptions(options ...string) func(string) error {
+	return func(property string) error {
+		if property == "" {
+			return nil
+		}
+		for _, option := range options {
+			if strings.ToLower(property) == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("property must be one of %s", strings.Join(options, ", "))
+	}
+}
+
+func checkSegments(property string) error {
+	if property == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(property); err != nil || n < 0 {
+		return errors.New("property must be a non-negative integer")
+	}
+	return nil
+}
+
+func checkTimeProperty(property string) error {
+	if property == "" || strings.ContainsAny(property, "0123456789") {
+		return nil
+	}
+	timeType, decimalType := getTimeOptions(property)
+	if timeType != "date" && timeType != "time" {
+		return errors.New("property must be date, time or a time layout")
+	}
+	switch decimalType {
+	case "", "ms", "us", "ns":
+		return nil
+	}
+	return errors.New("decimal of property must be one of ms, us, ns")
+}
+
+func checkElapsedProperty(property string) error {
+	if property == "" {
+		return nil
+	}
+	sinceType, unitType := getTimeOptions(property)
+	if sinceType != "start" && sinceType != "prev" {
+		return errors.New("property must be start or prev")
+	}
+	switch unitType {
+	case "", "s", "ms", "us", "ns":
+		return nil
+	}
+	return errors.New("unit of property must be one of s, ms, us, ns")
+}
+
+func checkEnvProperty(property string) error {
+	if property == "" {
+		return errors.New("property must be the name of an environment variable")
+	}
+	return nil
+}
@@ -1,7 +1,6 @@
 package text
 
 import (
-	"fmt"
 	"strconv"
 
 	"github.com/fufuok/gxlog/formatter/internal/util"
@@ -10,24 +9,21 @@ import (
 
 type fileFormatter struct {
 	segments int
-	fmtspec  string
+	spec     fmtSpec
 }
 
-func newFileFormatter(property, fmtspec string) elementFormatter {
+func newFileFormatter(property, fmtspec string) ElementFormatter {
 	if fmtspec == "" {
 		fmtspec = "%s"
 	}
 	segments, _ := strconv.Atoi(property)
 	return &fileFormatter{
 		segments: segments,
-		fmtspec:  fmtspec,
+		spec:     newFmtSpec(fmtspec),
 	}
 }
 
 func (formatter *fileFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
 	file := util.LastSegments(record.File, formatter.segments, '/')
-	if formatter.fmtspec == "%s" {
-		return append(buf, file...)
-	}
-	return append(buf, fmt.Sprintf(formatter.fmtspec, file)...)
+	return formatter.spec.AppendString(buf, file)
 }
@@ -0,0 +1,121 @@
+package text
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// A fmtSpec is the fmtspec of a builtin element. The common ones, which have
+// the verb 's' or 'd', an optional width and the optional flags '-' and '0',
+// e.g. %s, %-5s, %05d, are handled without the fmt package. Others are passed
+// to fmt.Sprintf.
+type fmtSpec struct {
+	spec  string
+	verb  byte
+	width int
+	minus bool
+	zero  bool
+	fast  bool
+}
+
+func newFmtSpec(spec string) fmtSpec {
+	fs := fmtSpec{spec: spec}
+	if len(spec) < 2 || spec[0] != '%' {
+		return fs
+	}
+	i := 1
+	for ; i < len(spec); i++ {
+		if spec[i] == '-' {
+			fs.minus = true
+		} else if spec[i] == '0' {
+			fs.zero = true
+		} else {
+			break
+		}
+	}
+	begin := i
+	for i < len(spec) && spec[i] >= '0' && spec[i] <= '9' {
+		i++
+	}
+	if i > begin {
+		fs.width, _ = strconv.Atoi(spec[begin:i])
+	}
+	if i != len(spec)-1 || (spec[i] != 's' && spec[i] != 'd') {
+		return fs
+	}
+	fs.verb = spec[i]
+	fs.fast = true
+	return fs
+}
+
+// AppendString appends the str formatted with the fmtSpec to the buf.
+func (fs *fmtSpec) AppendString(buf []byte, str string) []byte {
+	if !fs.fast || fs.verb != 's' {
+		return append(buf, fmt.Sprintf(fs.spec, str)...)
+	}
+	if fs.width == 0 {
+		return append(buf, str...)
+	}
+	begin := len(buf)
+	buf = append(buf, str...)
+	return fs.pad(buf, begin, utf8.RuneCountInString(str))
+}
+
+// AppendInt appends the n formatted with the fmtSpec to the buf.
+func (fs *fmtSpec) AppendInt(buf []byte, n int64) []byte {
+	if !fs.fast || fs.verb != 'd' {
+		return append(buf, fmt.Sprintf(fs.spec, n)...)
+	}
+	begin := len(buf)
+	buf = strconv.AppendInt(buf, n, 10)
+	if fs.width == 0 {
+		return buf
+	}
+	if n < 0 && fs.zero && !fs.minus {
+		// the zeros go after the sign
+		begin++
+		return fs.pad(buf, begin, len(buf)-begin+1)
+	}
+	return fs.pad(buf, begin, len(buf)-begin)
+}
+
+// FormatBytes formats the buf[begin:] as a string with the fmtSpec in place.
+func (fs *fmtSpec) FormatBytes(buf []byte, begin int) []byte {
+	if !fs.fast || fs.verb != 's' {
+		str := string(buf[begin:])
+		return append(buf[:begin], fmt.Sprintf(fs.spec, str)...)
+	}
+	if fs.width == 0 {
+		return buf
+	}
+	return fs.pad(buf, begin, utf8.RuneCount(buf[begin:]))
+}
+
+// pad pads the buf[begin:], whose width is the width, to the width of the
+// fmtSpec.
+func (fs *fmtSpec) pad(buf []byte, begin, width int) []byte {
+	count := fs.width - width
+	if count <= 0 {
+		return buf
+	}
+	if fs.minus {
+		for i := 0; i < count; i++ {
+			buf = append(buf, ' ')
+		}
+		return buf
+	}
+	end := len(buf)
+	for i := 0; i < count; i++ {
+		buf = append(buf, ' ')
+	}
+	copy(buf[begin+count:], buf[begin:end])
+	padding := byte(' ')
+	if fs.zero {
+		padding = '0'
+	}
+	for i := begin; i < begin+count; i++ {
+		buf[i] = padding
+	}
+	return buf
+}
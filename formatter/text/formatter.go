@@ -1,84 +1,166 @@
 // Package text implements a text formatter which implements the Formatter.
+//
+// Whether logs are colored is either set explicitly with Config.Coloring,
+// EnableColoring and DisableColoring, or decided by where logs are written to
+// with Config.ColorAuto or SetColorAuto. In the auto mode, link the formatter
+// returned by Formatter.ForWriter(w) to a slot whose writer writes to the w,
+// e.g. a console and a file in different slots, and logs are colored only if
+// ColorSupported(w) returns true.
 package text
 
 import (
+	"fmt"
+	"io"
+	"os"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/fufuok/gxlog/formatter/internal/util"
 	"github.com/fufuok/gxlog/iface"
 )
 
-var headerRegexp = regexp.MustCompile("{{([^:%]*?)(?::([^%]*?))?(%.*?)?}}")
+var headerRegexp = regexp.MustCompile(
+	`{{\?(.*?)}}|{{([^:%@]*?)(?::([^%@]*?))?(%[^@]*?)?(?:@(.*?))?}}`)
+
+var fmtspecRegexp = regexp.MustCompile(`%[-+# 0]*[0-9]*(?:\.[0-9]*)?[a-zA-Z]`)
 
-// A Formatter implements the interface iface.Formatter.
+// A Formatter implements the interface iface.Formatter, iface.Releaser and
+// iface.GoroutineIDUser.
 //
-// All methods of a Formatter are concurrency safe.
+// All methods of a Formatter are concurrency safe. Logs are formatted with an
+// immutable snapshot of the settings of the Formatter without locking, and
+// each setter replaces the snapshot with a modified copy atomically.
 // A Formatter MUST be created with New.
 type Formatter struct {
+	snapshot atomic.Value // *snapshot
+
+	lock sync.Mutex // serializes the setters
+}
+
+// A snapshot is the settings of a Formatter. It MUST NOT be modified once it
+// is stored to the Formatter.
+type snapshot struct {
 	header     string
 	minBufSize int
-	coloring   bool
+	coloring   bool // whether Format colors logs, see Config.ColorAuto
+	colorAuto  bool
+	colorMode  ColorMode
+	strict     bool
+	pooling    bool
+	elements   map[string]NewElementFunc
 
 	colorMgr  *colorMgr
+	msgProc   msgProcessor
 	appenders []*headerAppender
 	suffix    string
-
-	lock sync.Mutex
+	usesGID   bool
 }
 
 // New creates a new Formatter with the config.
 func New(config Config) *Formatter {
 	config.setDefaults()
-	formatter := &Formatter{
+	snap := &snapshot{
 		minBufSize: config.MinBufSize,
 		coloring:   config.Coloring,
+		colorAuto:  config.ColorAuto,
+		colorMode:  config.ColorMode,
+		strict:     config.Strict,
+		pooling:    config.Pooling,
 		colorMgr:   newColorMgr(),
+		msgProc: msgProcessor{
+			multiLine:  config.MultiLine,
+			linePrefix: config.LinePrefix,
+			maxMsgSize: config.MaxMsgSize,
+			ellipsis:   config.Ellipsis,
+		},
+	}
+	if err := snap.setHeader(config.Header); err != nil {
+		panic(err)
 	}
-	formatter.SetHeader(config.Header)
-	formatter.MapColors(config.ColorMap)
+	snap.colorMgr.MapColors(config.ColorMap)
+	if config.ColorAuto {
+		snap.coloring = ColorSupported(os.Stderr)
+	}
+	formatter := &Formatter{}
+	formatter.snapshot.Store(snap)
 	return formatter
 }
 
 // Header returns the header of the Formatter.
 func (formatter *Formatter) Header() string {
+	return formatter.load().header
+}
+
+// SetHeader sets the header of the Formatter.
+// For details of all supported fields in a header, see the comment of Config.
+// In strict mode, it returns an error if there is an unknown element or an
+// element with an invalid property in the header, and the header of the
+// Formatter is left to be unchanged. Otherwise, such elements are ignored.
+func (formatter *Formatter) SetHeader(header string) error {
 	formatter.lock.Lock()
 	defer formatter.lock.Unlock()
 
-	return formatter.header
+	snap := formatter.clone()
+	if err := snap.setHeader(header); err != nil {
+		return err
+	}
+	formatter.snapshot.Store(snap)
+	return nil
 }
 
-// SetHeader sets the header of the Formatter.
-// For details of all supported fields in a header, see the comment of Config.
-func (formatter *Formatter) SetHeader(header string) {
+// RegisterElement registers a custom element with the name for the Formatter
+// and then sets the header again to apply it. Names are case-insensitive.
+// A custom element of the Formatter takes precedence over a global one with
+// the same name. If the fn is nil, the custom element is unregistered.
+// It returns an error if the name is invalid, it is the name of a builtin
+// element, or the header fails to be set in strict mode.
+func (formatter *Formatter) RegisterElement(name string, fn NewElementFunc) error {
 	formatter.lock.Lock()
 	defer formatter.lock.Unlock()
 
-	formatter.header = header
-	formatter.appenders = formatter.appenders[:0]
-	var staticText string
-	for header != "" {
-		indexes := headerRegexp.FindStringSubmatchIndex(header)
-		if indexes == nil {
-			break
-		}
-		begin, end := indexes[0], indexes[1]
-		staticText += header[:begin]
-		element, property, fmtspec := extractElement(indexes, header)
-		if formatter.addAppender(element, property, fmtspec, staticText) {
-			staticText = ""
-		}
-		header = header[end:]
+	name, err := checkElementName(name)
+	if err != nil {
+		return fmt.Errorf("formatter/text.RegisterElement: %v", err)
+	}
+	snap := formatter.clone()
+	elements := make(map[string]NewElementFunc, len(snap.elements)+1)
+	for key, value := range snap.elements {
+		elements[key] = value
+	}
+	if fn == nil {
+		delete(elements, name)
+	} else {
+		elements[name] = fn
+	}
+	snap.elements = elements
+	if err := snap.setHeader(snap.header); err != nil {
+		return err
 	}
-	formatter.suffix = staticText + header
+	formatter.snapshot.Store(snap)
+	return nil
 }
 
-// MinBufSize returns the min buf size of the Formatter.
-func (formatter *Formatter) MinBufSize() int {
+// Strict returns whether the Formatter is in strict mode.
+func (formatter *Formatter) Strict() bool {
+	return formatter.load().strict
+}
+
+// SetStrict sets whether the Formatter is in strict mode. It takes effect on
+// headers set afterwards.
+func (formatter *Formatter) SetStrict(strict bool) {
 	formatter.lock.Lock()
 	defer formatter.lock.Unlock()
 
-	return formatter.minBufSize
+	snap := formatter.clone()
+	snap.strict = strict
+	formatter.snapshot.Store(snap)
+}
+
+// MinBufSize returns the min buf size of the Formatter.
+func (formatter *Formatter) MinBufSize() int {
+	return formatter.load().minBufSize
 }
 
 // SetMinBufSize sets the min buf size of the Formatter.
@@ -87,43 +169,176 @@ func (formatter *Formatter) SetMinBufSize(size int) {
 	formatter.lock.Lock()
 	defer formatter.lock.Unlock()
 
+	snap := formatter.clone()
 	if size == 0 {
-		formatter.minBufSize = 256
+		snap.minBufSize = 256
 	} else {
-		formatter.minBufSize = size
+		snap.minBufSize = size
 	}
+	formatter.snapshot.Store(snap)
 }
 
-// Coloring returns whether colorization is enabled in the Formatter.
-func (formatter *Formatter) Coloring() bool {
+// MultiLine returns the multi-line mode of the Formatter.
+func (formatter *Formatter) MultiLine() MultiLineMode {
+	return formatter.load().msgProc.multiLine
+}
+
+// SetMultiLine sets the multi-line mode of the Formatter.
+func (formatter *Formatter) SetMultiLine(mode MultiLineMode) {
 	formatter.lock.Lock()
 	defer formatter.lock.Unlock()
 
-	return formatter.coloring
+	snap := formatter.clone()
+	snap.msgProc.multiLine = mode
+	formatter.snapshot.Store(snap)
+}
+
+// LinePrefix returns the prefix of continuation lines in MultiLinePrefix mode.
+func (formatter *Formatter) LinePrefix() string {
+	return formatter.load().msgProc.linePrefix
 }
 
-// EnableColoring enables colorization in the Formatter.
+// SetLinePrefix sets the prefix of continuation lines in MultiLinePrefix mode.
+// If the prefix is empty, "\t" is used.
+func (formatter *Formatter) SetLinePrefix(prefix string) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	snap := formatter.clone()
+	if prefix == "" {
+		prefix = "\t"
+	}
+	snap.msgProc.linePrefix = prefix
+	formatter.snapshot.Store(snap)
+}
+
+// MaxMsgSize returns the max size in bytes of a message in the Formatter.
+func (formatter *Formatter) MaxMsgSize() int {
+	return formatter.load().msgProc.maxMsgSize
+}
+
+// SetMaxMsgSize sets the max size in bytes of a message in the Formatter.
+// The size must NOT be negative. If the size is 0, messages are never
+// truncated.
+func (formatter *Formatter) SetMaxMsgSize(size int) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	snap := formatter.clone()
+	snap.msgProc.maxMsgSize = size
+	formatter.snapshot.Store(snap)
+}
+
+// Ellipsis returns the ellipsis appended to a truncated message.
+func (formatter *Formatter) Ellipsis() string {
+	return formatter.load().msgProc.ellipsis
+}
+
+// SetEllipsis sets the ellipsis appended to a truncated message.
+// If the ellipsis is empty, "..." is used.
+func (formatter *Formatter) SetEllipsis(ellipsis string) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	snap := formatter.clone()
+	if ellipsis == "" {
+		ellipsis = "..."
+	}
+	snap.msgProc.ellipsis = ellipsis
+	formatter.snapshot.Store(snap)
+}
+
+// Coloring returns whether logs formatted by Format are colored.
+func (formatter *Formatter) Coloring() bool {
+	return formatter.load().coloring
+}
+
+// EnableColoring enables colorization in the Formatter and turns ColorAuto
+// off, such logs are colored wherever they are written to.
 func (formatter *Formatter) EnableColoring() {
 	formatter.lock.Lock()
 	defer formatter.lock.Unlock()
 
-	formatter.coloring = true
+	snap := formatter.clone()
+	snap.coloring = true
+	snap.colorAuto = false
+	formatter.snapshot.Store(snap)
 }
 
-// DisableColoring disables colorization in the Formatter.
+// DisableColoring disables colorization in the Formatter and turns ColorAuto
+// off, such logs are never colored.
 func (formatter *Formatter) DisableColoring() {
 	formatter.lock.Lock()
 	defer formatter.lock.Unlock()
 
-	formatter.coloring = false
+	snap := formatter.clone()
+	snap.coloring = false
+	snap.colorAuto = false
+	formatter.snapshot.Store(snap)
 }
 
-// Color returns the color of the level in the Formatter.
-func (formatter *Formatter) Color(level iface.Level) Color {
+// ColorAuto returns whether colorization is decided by where logs are
+// written to. For details, see the comment of Config.ColorAuto.
+func (formatter *Formatter) ColorAuto() bool {
+	return formatter.load().colorAuto
+}
+
+// SetColorAuto sets whether colorization is decided by where logs are written
+// to. For details, see the comment of Config.ColorAuto. If auto is false,
+// whether logs are colored is left to be unchanged for Format.
+func (formatter *Formatter) SetColorAuto(auto bool) {
 	formatter.lock.Lock()
 	defer formatter.lock.Unlock()
 
-	return formatter.colorMgr.Color(level)
+	snap := formatter.clone()
+	snap.colorAuto = auto
+	if auto {
+		snap.coloring = ColorSupported(os.Stderr)
+	}
+	formatter.snapshot.Store(snap)
+}
+
+// ColorMode returns the color mode of the Formatter.
+func (formatter *Formatter) ColorMode() ColorMode {
+	return formatter.load().colorMode
+}
+
+// UsesGoroutineID implements the interface GoroutineIDUser. It reports whether
+// the header contains the gid element.
+func (formatter *Formatter) UsesGoroutineID() bool {
+	return formatter.load().usesGID
+}
+
+// Pooling returns whether pooling is enabled in the Formatter.
+func (formatter *Formatter) Pooling() bool {
+	return formatter.load().pooling
+}
+
+// SetPooling sets whether pooling is enabled in the Formatter. For details,
+// see the comment of Config.Pooling.
+func (formatter *Formatter) SetPooling(pooling bool) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	snap := formatter.clone()
+	snap.pooling = pooling
+	formatter.snapshot.Store(snap)
+}
+
+// SetColorMode sets the color mode of the Formatter. It takes effect only if
+// colorization is enabled.
+func (formatter *Formatter) SetColorMode(mode ColorMode) {
+	formatter.lock.Lock()
+	defer formatter.lock.Unlock()
+
+	snap := formatter.clone()
+	snap.colorMode = mode
+	formatter.snapshot.Store(snap)
+}
+
+// Color returns the color of the level in the Formatter.
+func (formatter *Formatter) Color(level iface.Level) Color {
+	return formatter.load().colorMgr.Color(level)
 }
 
 // SetColor sets the color of the level in the Formatter.
@@ -131,7 +346,10 @@ func (formatter *Formatter) SetColor(level iface.Level, color Color) {
 	formatter.lock.Lock()
 	defer formatter.lock.Unlock()
 
-	formatter.colorMgr.SetColor(level, color)
+	snap := formatter.clone()
+	snap.colorMgr = snap.colorMgr.Clone()
+	snap.colorMgr.SetColor(level, color)
+	formatter.snapshot.Store(snap)
 }
 
 // MapColors maps the color of levels in the Formatter according to the colorMap.
@@ -140,15 +358,15 @@ func (formatter *Formatter) MapColors(colorMap map[iface.Level]Color) {
 	formatter.lock.Lock()
 	defer formatter.lock.Unlock()
 
-	formatter.colorMgr.MapColors(colorMap)
+	snap := formatter.clone()
+	snap.colorMgr = snap.colorMgr.Clone()
+	snap.colorMgr.MapColors(colorMap)
+	formatter.snapshot.Store(snap)
 }
 
 // MarkedColor returns the color of a log that is marked.
 func (formatter *Formatter) MarkedColor() Color {
-	formatter.lock.Lock()
-	defer formatter.lock.Unlock()
-
-	return formatter.colorMgr.MarkedColor()
+	return formatter.load().colorMgr.MarkedColor()
 }
 
 // SetMarkedColor sets the color of a log that is marked.
@@ -156,53 +374,194 @@ func (formatter *Formatter) SetMarkedColor(color Color) {
 	formatter.lock.Lock()
 	defer formatter.lock.Unlock()
 
-	formatter.colorMgr.SetMarkedColor(color)
+	snap := formatter.clone()
+	snap.colorMgr = snap.colorMgr.Clone()
+	snap.colorMgr.SetMarkedColor(color)
+	formatter.snapshot.Store(snap)
+}
+
+// ForWriter returns a formatter for the slot whose writer writes logs to the w.
+// It formats logs with the Formatter, except that if ColorAuto is enabled in
+// the Formatter, logs are colored only if ColorSupported(w) returns true, which
+// is evaluated once when ForWriter is called. Thus one Formatter can serve
+// both a console and a file in different slots. If ColorAuto is disabled,
+// logs are colored as Format does.
+func (formatter *Formatter) ForWriter(w io.Writer) iface.Formatter {
+	return &slotFormatter{
+		formatter:      formatter,
+		colorSupported: ColorSupported(w),
+	}
 }
 
 // Format implements the interface Formatter. It formats a Record.
+// If pooling is enabled, the returned bytes come from a pool and they should
+// be released with Release after they are written.
 func (formatter *Formatter) Format(record *iface.Record) []byte {
-	formatter.lock.Lock()
-	defer formatter.lock.Unlock()
+	snap := formatter.load()
+	return snap.format(record, snap.coloring)
+}
+
+// Release implements the interface Releaser. It puts the bs returned by Format
+// back to the pool if pooling was enabled when the bs was formatted, otherwise
+// it does nothing. The bs must NOT be used after it is released.
+func (formatter *Formatter) Release(bs []byte) {
+	util.ReleaseBuffer(bs)
+}
+
+func (formatter *Formatter) load() *snapshot {
+	return formatter.snapshot.Load().(*snapshot)
+}
+
+// clone returns a copy of the current snapshot. It MUST be called with the
+// lock held.
+func (formatter *Formatter) clone() *snapshot {
+	snap := *formatter.load()
+	return &snap
+}
 
+func (snap *snapshot) format(record *iface.Record, coloring bool) []byte {
 	var left, right []byte
-	if formatter.coloring {
+	var mgr *colorMgr
+	if coloring && snap.colorMode == ElementColor {
+		mgr = snap.colorMgr
+	} else if coloring {
 		if record.Aux.Marked {
-			left, right = formatter.colorMgr.MarkedColorEars()
+			left, right = snap.colorMgr.MarkedColorEars()
 		} else {
-			left, right = formatter.colorMgr.ColorEars(record.Level)
+			left, right = snap.colorMgr.ColorEars(record.Level)
 		}
 	}
 
-	buf := make([]byte, 0, formatter.minBufSize)
+	var buf []byte
+	if snap.pooling {
+		buf = util.GetBuffer(snap.minBufSize)
+	} else {
+		buf = make([]byte, 0, snap.minBufSize)
+	}
 	buf = append(buf, left...)
-	for _, appender := range formatter.appenders {
-		buf = appender.AppendHeader(buf, record)
+	for _, appender := range snap.appenders {
+		buf = appender.AppendHeader(buf, record, mgr, &snap.msgProc, len(left))
 	}
-	buf = append(buf, formatter.suffix...)
+	buf = append(buf, snap.suffix...)
 	buf = append(buf, right...)
 
+	if !snap.pooling {
+		return util.Unpooled(buf)
+	}
 	return buf
 }
 
-func (formatter *Formatter) addAppender(element, property, fmtspec,
-	staticText string) bool {
+type slotFormatter struct {
+	formatter      *Formatter
+	colorSupported bool
+}
 
-	appender := newHeaderAppender(element, property, fmtspec, staticText)
-	if appender == nil {
-		return false
+func (slot *slotFormatter) Format(record *iface.Record) []byte {
+	snap := slot.formatter.load()
+	coloring := snap.coloring
+	if snap.colorAuto {
+		coloring = slot.colorSupported
 	}
-	formatter.appenders = append(formatter.appenders, appender)
-	return true
+	return snap.format(record, coloring)
+}
+
+func (slot *slotFormatter) UsesGoroutineID() bool {
+	return slot.formatter.UsesGoroutineID()
 }
 
-func extractElement(indexes []int, header string) (element, property, fmtspec string) {
+func (slot *slotFormatter) Release(bs []byte) {
+	slot.formatter.Release(bs)
+}
+
+func (snap *snapshot) setHeader(header string) error {
+	var appenders []*headerAppender
+	var staticText string
+	rest := header
+	for rest != "" {
+		indexes := headerRegexp.FindStringSubmatchIndex(rest)
+		if indexes == nil {
+			break
+		}
+		begin, end := indexes[0], indexes[1]
+		staticText += rest[:begin]
+		var appender *headerAppender
+		var err error
+		if indexes[2] >= 0 {
+			cond := extractCondition(rest[indexes[2]:indexes[3]])
+			appender, err = newCondAppender(cond.element, cond.property,
+				cond.fmtspec, cond.before, cond.after, cond.hide, staticText,
+				snap.elements, snap.strict)
+		} else {
+			element, property, fmtspec, sty := extractElement(indexes[2:], rest)
+			appender, err = newHeaderAppender(element, property, fmtspec, sty,
+				staticText, snap.elements, snap.strict)
+		}
+		if err == nil {
+			appenders = append(appenders, appender)
+			staticText = ""
+		} else if snap.strict {
+			return fmt.Errorf("formatter/text.SetHeader: %v", err)
+		}
+		rest = rest[end:]
+	}
+	snap.header = header
+	snap.appenders = appenders
+	snap.suffix = staticText + rest
+	snap.usesGID = false
+	for _, appender := range appenders {
+		if appender.usesGID {
+			snap.usesGID = true
+		}
+	}
+	return nil
+}
+
+func extractElement(indexes []int, header string) (element, property, fmtspec,
+	sty string) {
+
 	element = strings.ToLower(getField(header, indexes[2], indexes[3]))
 	property = getField(header, indexes[4], indexes[5])
 	fmtspec = getField(header, indexes[6], indexes[7])
+	sty = getField(header, indexes[8], indexes[9])
 	if fmtspec == "%" {
 		fmtspec = ""
 	}
-	return element, property, fmtspec
+	return element, property, fmtspec, sty
+}
+
+type condSpec struct {
+	element  string
+	property string
+	fmtspec  string
+	before   string
+	after    string
+	hide     bool
+}
+
+// extractCondition extracts a conditional element from its content, which is
+// in the pattern of <name>[:property]:<text>. The text before the fmtspec in the
+// text must NOT contain any colon, since the last colon before the fmtspec ends
+// the property. If there is no fmtspec in the text, the element itself is not
+// output and the last colon in the text ends the property.
+func extractCondition(content string) condSpec {
+	colon := strings.IndexByte(content, ':')
+	if colon < 0 {
+		return condSpec{element: strings.ToLower(strings.TrimSpace(content))}
+	}
+	spec := condSpec{element: strings.ToLower(strings.TrimSpace(content[:colon]))}
+	text := content[colon+1:]
+	loc := fmtspecRegexp.FindStringIndex(text)
+	if loc == nil {
+		loc = []int{len(text), len(text)}
+		spec.hide = true
+	}
+	if colon = strings.LastIndexByte(text[:loc[0]], ':'); colon >= 0 {
+		spec.property = strings.TrimSpace(text[:colon])
+	}
+	spec.before = text[colon+1 : loc[0]]
+	spec.fmtspec = text[loc[0]:loc[1]]
+	spec.after = text[loc[1]:]
+	return spec
 }
 
 func getField(header string, begin, end int) string {
@@ -1,9 +1,15 @@
 package text_test
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -107,6 +113,120 @@ func TestBizarreHeader(t *testing.T) {
 	testFormat(t, formatter, &tmplRecord, expect)
 }
 
+func TestProcessHeader(t *testing.T) {
+	os.Setenv("GXLOG_TEST_ENV", "staging")
+	defer os.Unsetenv("GXLOG_TEST_ENV")
+
+	formatter := text.New(text.Config{
+		Header: "{{host}} {{app}} {{pid}} {{pid%08d}} {{gid}} {{env:GXLOG_TEST_ENV}} " +
+			"{{env:GXLOG_TEST_ENV%-8s}}|{{msg}}",
+	})
+	host, _ := os.Hostname()
+	record := cloneRecord()
+	record.GID = 18
+	expect := fmt.Sprintf("%s %s %d %08d 18 staging staging |%s", host,
+		filepath.Base(os.Args[0]), os.Getpid(), os.Getpid(), tmplMsg)
+	testFormat(t, formatter, record, expect)
+}
+
+func TestElapsedHeader(t *testing.T) {
+	formatter := text.New(text.Config{
+		Header: "{{elapsed:prev.ms}} {{msg}}",
+	})
+	record := cloneRecord()
+	testFormat(t, formatter, record, "0s "+tmplMsg)
+	record.Time = record.Time.Add(1500*time.Millisecond + 300*time.Microsecond)
+	testFormat(t, formatter, record, "1.5s "+tmplMsg)
+
+	formatter.SetHeader("{{elapsed:start.s%6s}}")
+	record.Time = time.Now().Add(time.Hour)
+	testFormat(t, formatter, record, "1h0m0s")
+}
+
+type upperFormatter struct {
+	fmtspec string
+}
+
+func (formatter *upperFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
+	return append(buf, fmt.Sprintf(formatter.fmtspec, strings.ToUpper(record.Msg))...)
+}
+
+func newUpperFormatter(property, fmtspec string) (text.ElementFormatter, error) {
+	if property != "" {
+		return nil, errors.New("no property is supported")
+	}
+	if fmtspec == "" {
+		fmtspec = "%s"
+	}
+	return &upperFormatter{fmtspec: fmtspec}, nil
+}
+
+func TestCustomElement(t *testing.T) {
+	if err := text.RegisterElement("Upper", newUpperFormatter); err != nil {
+		t.Fatal(err)
+	}
+	defer text.RegisterElement("upper", nil)
+	if err := text.RegisterElement("msg", newUpperFormatter); err == nil {
+		t.Error("TestCustomElement: expect an error for a builtin element")
+	}
+
+	formatter := text.New(text.Config{Header: "{{upper%8s}}|{{upper:x}}|{{msg}}"})
+	testFormat(t, formatter, &tmplRecord, fmt.Sprintf("%8s||%s", "TESTING", tmplMsg))
+
+	err := formatter.RegisterElement("upper", func(_, _ string) (text.ElementFormatter, error) {
+		return &upperFormatter{fmtspec: "<%s>"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testFormat(t, formatter, &tmplRecord, fmt.Sprintf("<TESTING>|<TESTING>|%s", tmplMsg))
+}
+
+func TestStrict(t *testing.T) {
+	formatter := text.New(text.Config{Header: "{{msg}}", Strict: true})
+	for _, header := range []string{"{{unknown}}", "{{level:short}}", "{{file:-1}}",
+		"{{time:hour}}", "{{elapsed:start.m}}", "{{env}}"} {
+		if err := formatter.SetHeader(header); err == nil {
+			t.Errorf("TestStrict: expect an error for %q", header)
+		}
+	}
+	testFormat(t, formatter, &tmplRecord, tmplMsg)
+	if err := formatter.SetHeader("{{level:char}} {{file:1}} {{time:time.ms}}"); err != nil {
+		t.Error(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("TestStrict: expect New to panic")
+		}
+	}()
+	text.New(text.Config{Header: "{{unknown}}", Strict: true})
+}
+
+func TestCtxElement(t *testing.T) {
+	record := cloneRecord()
+	record.Aux.Contexts = append(record.Aux.Contexts, iface.Context{Key: "reqid", Value: "abc"})
+	testCases := []struct {
+		Header string
+		Expect string
+	}{
+		{"[{{ctx:reqid}}] {{msg}} {{ctx:*-reqid}}", "[abc] testing (k1: v1) (k2: v2)"},
+		{"{{ctx:user|-}} {{ctx:user|}}|{{ctx:k2,reqid:kv}}", "- |k2=v2 reqid=abc"},
+		{"{{ctx:*:list%-30s}}|", "k1: v1, k2: v2, reqid: abc    |"},
+		{"{{ctx:*-k1,k2,reqid|none:kv}} {{context:kv}}", "none k1=v1 k2=v2 reqid=abc"},
+	}
+	for _, testCase := range testCases {
+		formatter := text.New(text.Config{Header: testCase.Header, Strict: true})
+		testFormat(t, formatter, record, testCase.Expect)
+	}
+	formatter := text.New(text.Config{Strict: true})
+	for _, header := range []string{"{{ctx}}", "{{ctx:reqid:map}}"} {
+		if err := formatter.SetHeader(header); err == nil {
+			t.Errorf("TestCtxElement: expect an error for %q", header)
+		}
+	}
+}
+
 func TestColor(t *testing.T) {
 	formatter := text.New(text.Config{
 		Header:   "{{msg}}",
@@ -130,6 +250,253 @@ func TestColor(t *testing.T) {
 	testFormat(t, formatter, record, expect)
 }
 
+func TestConditionalElement(t *testing.T) {
+	record := cloneRecord()
+	record.Aux.Contexts = append(record.Aux.Contexts, iface.Context{Key: "reqid", Value: "abc"})
+	testCases := []struct {
+		Header string
+		Expect string
+	}{
+		{"{{msg}}{{?context: [%s]}}|", "testing [(k1: v1) (k2: v2) (reqid: abc)]|"},
+		{"{{?ctx:reqid: reqid=%s;}}{{?ctx:user: user=%s;}}", " reqid=abc;"},
+		{"{{?ctx:*:kv: <%-8s>}}", " <k1=v1 k2=v2 reqid=abc>"},
+		{"{{?prefix:%q }}{{mark}} {{mark:M}}{{?mark: <!>}}", "\"**** \" MARKED M <!>"},
+		{"{{?line:line %04d}}", "line 0064"},
+	}
+	for _, testCase := range testCases {
+		formatter := text.New(text.Config{Header: testCase.Header, Strict: true})
+		testFormat(t, formatter, record, testCase.Expect)
+	}
+
+	record.Aux.Prefix = ""
+	record.Aux.Marked = false
+	record.Aux.Contexts = nil
+	formatter := text.New(text.Config{
+		Header: "{{?prefix:%q }}{{mark}}{{?mark: <!>}}{{?context: [%s]}} {{msg}}",
+		Strict: true,
+	})
+	testFormat(t, formatter, record, " testing")
+	if err := formatter.SetHeader("{{?level:full: %s}}{{?unknown: %s}}"); err == nil {
+		t.Error("TestConditionalElement: expect an error for an unknown element")
+	}
+}
+
+func TestElementColor(t *testing.T) {
+	record := cloneRecord()
+	record.Aux.Marked = false
+	formatter := text.New(text.Config{
+		Header:    "{{level:char@level,bold}} {{line@dim}} {{msg@#ff8800,bg-208}}",
+		Coloring:  true,
+		ColorMode: text.ElementColor,
+		Strict:    true,
+	})
+	expect := fmt.Sprintf("\033[1;%dmI\033[0m \033[2m%d\033[0m "+
+		"\033[38;2;255;136;0;48;5;208m%s\033[0m", text.Blue, tmplLine, tmplMsg)
+	testFormat(t, formatter, record, expect)
+
+	formatter.SetColorMode(text.LineColor)
+	expect = fmt.Sprintf("\033[%dmI %d %s\033[0m", text.Blue, tmplLine, tmplMsg)
+	testFormat(t, formatter, record, expect)
+
+	formatter.SetColor(iface.Info, text.Color256(99))
+	expect = fmt.Sprintf("\033[38;5;99mI %d %s\033[0m", tmplLine, tmplMsg)
+	testFormat(t, formatter, record, expect)
+
+	for _, header := range []string{"{{msg@pink}}", "{{msg@#12345}}", "{{msg@256}}"} {
+		if err := formatter.SetHeader(header); err == nil {
+			t.Errorf("TestElementColor: expect an error for %q", header)
+		}
+	}
+}
+
+func TestMultiLine(t *testing.T) {
+	record := cloneRecord()
+	record.Msg = "panic: oops\ngoroutine 1:\n\tmain.go:7\n"
+	testCases := []struct {
+		Config text.Config
+		Expect string
+	}{
+		{text.Config{Header: "{{level:char}} {{msg}}|"},
+			"I panic: oops\ngoroutine 1:\n\tmain.go:7\n|"},
+		{text.Config{Header: "{{level:char}} {{msg}}|", MultiLine: text.MultiLinePrefix},
+			"I panic: oops\n\tgoroutine 1:\n\t\tmain.go:7\n|"},
+		{text.Config{Header: "{{level:char}} {{msg}}|", MultiLine: text.MultiLinePrefix,
+			LinePrefix: "> "}, "I panic: oops\n> goroutine 1:\n> \tmain.go:7\n|"},
+		{text.Config{Header: "{{level:char}} {{msg}}|", MultiLine: text.MultiLineHeader},
+			"I panic: oops\nI goroutine 1:\nI \tmain.go:7\n|"},
+		{text.Config{Header: "{{level:char}} {{msg}}|", MultiLine: text.MultiLineEscape},
+			"I panic: oops\\ngoroutine 1:\\n\tmain.go:7\\n|"},
+		{text.Config{Header: "{{msg}}|", MaxMsgSize: 5}, "panic...|"},
+		{text.Config{Header: "{{msg}}|", MaxMsgSize: 16, Ellipsis: "~",
+			MultiLine: text.MultiLineEscape}, `panic: oops\ngoro~|`},
+		{text.Config{Header: "{{msg%-12s}}|", MaxMsgSize: 5}, "panic...    |"},
+		{text.Config{Header: "{{msg%q}}|", MaxMsgSize: 5}, `"panic..."|`},
+	}
+	for _, testCase := range testCases {
+		testFormat(t, text.New(testCase.Config), record, testCase.Expect)
+	}
+
+	record.Msg = "日本語"
+	formatter := text.New(text.Config{Header: "{{msg}}"})
+	formatter.SetMaxMsgSize(4)
+	testFormat(t, formatter, record, "日...")
+}
+
+func TestColorSupported(t *testing.T) {
+	for _, key := range []string{"NO_COLOR", "FORCE_COLOR", "TERM"} {
+		defer setenv(key, os.Getenv(key))
+	}
+	file, err := ioutil.TempFile("", "gxlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	testCases := []struct {
+		NoColor    string
+		ForceColor string
+		Expect     bool
+	}{
+		{"", "", false},
+		{"", "1", true},
+		{"", "0", false},
+		{"1", "1", false},
+	}
+	for _, testCase := range testCases {
+		setenv("NO_COLOR", testCase.NoColor)
+		setenv("FORCE_COLOR", testCase.ForceColor)
+		if text.IsTerminal(file) {
+			t.Error("TestColorSupported: a regular file is not a terminal")
+		}
+		if text.ColorSupported(file) != testCase.Expect {
+			t.Errorf("TestColorSupported: expect %v with NO_COLOR=%q FORCE_COLOR=%q",
+				testCase.Expect, testCase.NoColor, testCase.ForceColor)
+		}
+	}
+
+	formatter := text.New(text.Config{Header: "{{msg}}", ColorAuto: true})
+	setenv("NO_COLOR", "")
+	setenv("FORCE_COLOR", "")
+	testFormat(t, formatter.ForWriter(file), &tmplRecord, tmplMsg)
+	setenv("FORCE_COLOR", "1")
+	console := formatter.ForWriter(file)
+	expect := fmt.Sprintf("\033[%dm%s\033[0m", text.Magenta, tmplMsg)
+	testFormat(t, console, &tmplRecord, expect)
+	formatter.DisableColoring()
+	testFormat(t, console, &tmplRecord, tmplMsg)
+	formatter.SetColorAuto(true)
+	testFormat(t, console, &tmplRecord, expect)
+	testFormat(t, formatter, &tmplRecord, expect)
+
+	// an explicit setting is NOT overridden by the writer
+	setenv("FORCE_COLOR", "")
+	formatter.EnableColoring()
+	testFormat(t, formatter.ForWriter(file), &tmplRecord, expect)
+}
+
+func TestFmtSpec(t *testing.T) {
+	record := cloneRecord()
+	record.Line = -7
+	formatter := text.New(text.Config{
+		Header: "[{{level:char%-3s}}][{{line%05d}}][{{line%-4d}}][{{func%5s}}][{{pkg%.4s}}]",
+	})
+	testFormat(t, formatter, record, "[I  ][-0007][-7  ][ Test][gith]")
+}
+
+func TestTogglePooling(t *testing.T) {
+	formatter := text.New(text.Config{Header: "{{msg}}\n"})
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		record := cloneRecord()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			formatter.Release(formatter.Format(record))
+		}
+	}()
+
+	record := cloneRecord()
+	for i := 0; i < 1000; i++ {
+		formatter.SetPooling(false)
+		record.Msg = strconv.Itoa(i)
+		bs := formatter.Format(record)
+		expect := string(bs)
+		formatter.SetPooling(true)
+		// the bs must NOT be put back to the pool to be reused
+		formatter.Release(bs)
+		formatter.Release(formatter.Format(cloneRecord()))
+		if string(bs) != expect {
+			t.Fatalf("TestTogglePooling:\noutput: %q\nexpect: %q", bs, expect)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func BenchmarkFormat(b *testing.B) {
+	formatter := text.New(text.Config{
+		Header:  "{{time:time.us}} {{level%-5s}} {{file:1}}:{{line%04d}} {{func}} {{msg}}\n",
+		Pooling: true,
+	})
+	record := cloneRecord()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			formatter.Release(formatter.Format(record))
+		}
+	})
+}
+
+func TestLevelStyleColors(t *testing.T) {
+	formatter := text.New(text.Config{
+		Header:    "{{level@level}} {{msg}}",
+		Coloring:  true,
+		ColorMode: text.ElementColor,
+		ColorMap:  map[iface.Level]text.Color{iface.Info: text.TrueColor(1, 2, 3)},
+	})
+	record := cloneRecord()
+	record.Aux.Marked = false
+	testFormat(t, formatter, record, "\033[38;2;1;2;3mINFO \033[0m "+tmplMsg)
+	formatter.SetColor(iface.Info, text.Color256(208))
+	testFormat(t, formatter, record, "\033[38;5;208mINFO \033[0m "+tmplMsg)
+	record.Aux.Marked = true
+	formatter.SetMarkedColor(text.Color256(99))
+	testFormat(t, formatter, record, "\033[38;5;99mINFO \033[0m "+tmplMsg)
+}
+
+func BenchmarkFormatElementColor(b *testing.B) {
+	formatter := text.New(text.Config{
+		Header:    "{{time:time.us}} {{level@level,bold}} {{msg}}\\n",
+		Coloring:  true,
+		ColorMode: text.ElementColor,
+		ColorMap:  map[iface.Level]text.Color{iface.Info: text.TrueColor(1, 2, 3)},
+		Pooling:   true,
+	})
+	record := cloneRecord()
+	record.Aux.Marked = false
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		formatter.Release(formatter.Format(record))
+	}
+}
+
+func setenv(key, value string) {
+	if value == "" {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, value)
+	}
+}
+
 func testFormat(t *testing.T, formatter iface.Formatter, record *iface.Record,
 	expect string) {
 
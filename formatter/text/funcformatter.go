@@ -1,7 +1,6 @@
 package text
 
 import (
-	"fmt"
 	"strconv"
 
 	"github.com/fufuok/gxlog/formatter/internal/util"
@@ -10,24 +9,21 @@ import (
 
 type funcFormatter struct {
 	segments int
-	fmtspec  string
+	spec     fmtSpec
 }
 
-func newFuncFormatter(property, fmtspec string) elementFormatter {
+func newFuncFormatter(property, fmtspec string) ElementFormatter {
 	if fmtspec == "" {
 		fmtspec = "%s"
 	}
 	segments, _ := strconv.Atoi(property)
 	return &funcFormatter{
 		segments: segments,
-		fmtspec:  fmtspec,
+		spec:     newFmtSpec(fmtspec),
 	}
 }
 
 func (formatter *funcFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
 	fn := util.LastSegments(record.Func, formatter.segments, '.')
-	if formatter.fmtspec == "%s" {
-		return append(buf, fn...)
-	}
-	return append(buf, fmt.Sprintf(formatter.fmtspec, fn)...)
+	return formatter.spec.AppendString(buf, fn)
 }
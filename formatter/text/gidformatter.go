@@ -0,0 +1,20 @@
+package text
+
+import (
+	"github.com/fufuok/gxlog/iface"
+)
+
+type gidFormatter struct {
+	spec fmtSpec
+}
+
+func newGIDFormatter(_, fmtspec string) ElementFormatter {
+	if fmtspec == "" {
+		fmtspec = "%d"
+	}
+	return &gidFormatter{spec: newFmtSpec(fmtspec)}
+}
+
+func (formatter *gidFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
+	return formatter.spec.AppendInt(buf, record.GID)
+}
@@ -1,14 +1,12 @@
 package text
 
 import (
+	"fmt"
+
 	"github.com/fufuok/gxlog/iface"
 )
 
-type elementFormatter interface {
-	FormatElement(buf []byte, record *iface.Record) []byte
-}
-
-var newFormatterFuncMap = map[string]func(property, fmtspec string) elementFormatter{
+var newFormatterFuncMap = map[string]func(property, fmtspec string) ElementFormatter{
 	"time":    newTimeFormatter,
 	"level":   newLevelFormatter,
 	"file":    newFileFormatter,
@@ -18,25 +16,150 @@ var newFormatterFuncMap = map[string]func(property, fmtspec string) elementForma
 	"msg":     newMsgFormatter,
 	"prefix":  newPrefixFormatter,
 	"context": newContextFormatter,
+	"ctx":     newCtxFormatter,
+	"host":    newHostFormatter,
+	"pid":     newPidFormatter,
+	"gid":     newGIDFormatter,
+	"elapsed": newElapsedFormatter,
+	"app":     newAppFormatter,
+	"env":     newEnvFormatter,
+	"mark":    newMarkFormatter,
 }
 
 type headerAppender struct {
-	formatter  elementFormatter
+	formatter  ElementFormatter
+	style      *style
 	staticText string
+	isMsg      bool
+	usesGID    bool
+	cond       *condition
 }
 
-func newHeaderAppender(element, property, fmtspec, staticText string) *headerAppender {
-	newFunc := newFormatterFuncMap[element]
-	if newFunc == nil {
-		return nil
+// A condition makes an element optional. If the output of the element with its
+// default fmtspec is empty, nothing is output. Otherwise, the element is output
+// between the before and the after, or only the before and the after are
+// output if hide is true.
+type condition struct {
+	plain  ElementFormatter // nil if the fmtspec of the element is the default
+	before string
+	after  string
+	hide   bool
+}
+
+// newHeaderAppender creates a headerAppender of the element. The custom
+// elements take precedence over the global custom elements. It returns an
+// error if the element is unknown or its property is invalid. The property of
+// a builtin element is checked only if strict is true. An invalid style is an
+// error if strict is true, otherwise it is ignored.
+func newHeaderAppender(element, property, fmtspec, styleStr, staticText string,
+	custom map[string]NewElementFunc, strict bool) (*headerAppender, error) {
+
+	sty, err := parseStyle(styleStr)
+	if err != nil && strict {
+		return nil, fmt.Errorf("element %q: %v", element, err)
+	}
+	formatter, err := newElementFormatter(element, property, fmtspec, custom, strict)
+	if err != nil {
+		return nil, err
 	}
 	return &headerAppender{
-		formatter:  newFunc(property, fmtspec),
+		formatter:  formatter,
+		style:      sty,
 		staticText: staticText,
+		isMsg:      element == "msg",
+		usesGID:    element == "gid",
+	}, nil
+}
+
+// newCondAppender creates a headerAppender of the conditional element. The
+// before and the after are output around the element only if the element is
+// not empty. If hide is true, the element itself is not output.
+func newCondAppender(element, property, fmtspec, before, after string, hide bool,
+	staticText string, custom map[string]NewElementFunc, strict bool) (*headerAppender, error) {
+
+	appender, err := newHeaderAppender(element, property, fmtspec, "", staticText,
+		custom, strict)
+	if err != nil {
+		return nil, err
+	}
+	cond := &condition{before: before, after: after, hide: hide}
+	if fmtspec != "" || hide {
+		if cond.plain, err = newElementFormatter(element, property, "", custom,
+			strict); err != nil {
+			return nil, err
+		}
 	}
+	appender.cond = cond
+	return appender, nil
 }
 
-func (appender *headerAppender) AppendHeader(buf []byte, record *iface.Record) []byte {
+func newElementFormatter(element, property, fmtspec string,
+	custom map[string]NewElementFunc, strict bool) (ElementFormatter, error) {
+
+	if newFunc := newFormatterFuncMap[element]; newFunc != nil {
+		if check := propertyCheckers[element]; strict && check != nil {
+			if err := check(property); err != nil {
+				return nil, fmt.Errorf("element %q: %v", element, err)
+			}
+		}
+		return newFunc(property, fmtspec), nil
+	}
+	newFunc := custom[element]
+	if newFunc == nil {
+		newFunc = lookupElement(element)
+	}
+	if newFunc == nil {
+		return nil, fmt.Errorf("unknown element %q", element)
+	}
+	formatter, err := newFunc(property, fmtspec)
+	if err != nil {
+		return nil, fmt.Errorf("element %q: %v", element, err)
+	}
+	return formatter, nil
+}
+
+// AppendHeader appends the static text and the element to the buf. If the mgr
+// is not nil and the appender has a style, the element is colored. If the
+// element is the msg, it is truncated and processed by the proc and the
+// buf[lineStart:] before the element is used as the header of the log. If the element is conditional
+// and empty, only the static text is appended.
+func (appender *headerAppender) AppendHeader(buf []byte, record *iface.Record,
+	mgr *colorMgr, proc *msgProcessor, lineStart int) []byte {
+
 	buf = append(buf, appender.staticText...)
-	return appender.formatter.FormatElement(buf, record)
+	headerEnd := len(buf)
+	cond := appender.cond
+	if cond != nil {
+		if cond.plain != nil {
+			if len(cond.plain.FormatElement(buf, record)) == len(buf) {
+				return buf
+			}
+		}
+		buf = append(buf, cond.before...)
+		if cond.hide {
+			return append(buf, cond.after...)
+		}
+	}
+	styled := mgr != nil && appender.style != nil
+	if styled {
+		buf = appender.style.appendLeft(buf, mgr, record)
+	}
+	if appender.isMsg {
+		record = proc.Truncate(record)
+	}
+	begin := len(buf)
+	buf = appender.formatter.FormatElement(buf, record)
+	if cond != nil && cond.plain == nil && len(buf) == begin {
+		return buf[:headerEnd]
+	}
+	if appender.isMsg {
+		buf = proc.Process(buf, lineStart, headerEnd, begin)
+	}
+	if styled {
+		buf = append(buf, mgr.resetSeq...)
+	}
+	if cond != nil {
+		buf = append(buf, cond.after...)
+	}
+	return buf
 }
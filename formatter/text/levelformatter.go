@@ -1,7 +1,6 @@
 package text
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/fufuok/gxlog/iface"
@@ -27,25 +26,21 @@ var levelDescChar = []string{
 
 type levelFormatter struct {
 	descList []string
-	fmtspec  string
+	spec     fmtSpec
 }
 
-func newLevelFormatter(property, fmtspec string) elementFormatter {
+func newLevelFormatter(property, fmtspec string) ElementFormatter {
 	if fmtspec == "" {
 		fmtspec = "%s"
 	}
 	return &levelFormatter{
 		descList: selectDescList(property),
-		fmtspec:  fmtspec,
+		spec:     newFmtSpec(fmtspec),
 	}
 }
 
 func (formatter *levelFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
-	desc := formatter.descList[record.Level]
-	if formatter.fmtspec == "%s" {
-		return append(buf, desc...)
-	}
-	return append(buf, fmt.Sprintf(formatter.fmtspec, desc)...)
+	return formatter.spec.AppendString(buf, formatter.descList[record.Level])
 }
 
 func selectDescList(property string) []string {
@@ -1,27 +1,20 @@
 package text
 
 import (
-	"fmt"
-	"strconv"
-
 	"github.com/fufuok/gxlog/iface"
 )
 
 type lineFormatter struct {
-	property string
-	fmtspec  string
+	spec fmtSpec
 }
 
-func newLineFormatter(property, fmtspec string) elementFormatter {
+func newLineFormatter(_, fmtspec string) ElementFormatter {
 	if fmtspec == "" {
 		fmtspec = "%d"
 	}
-	return &lineFormatter{property: property, fmtspec: fmtspec}
+	return &lineFormatter{spec: newFmtSpec(fmtspec)}
 }
 
 func (formatter *lineFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
-	if formatter.fmtspec == "%d" {
-		return strconv.AppendInt(buf, int64(record.Line), 10)
-	}
-	return append(buf, fmt.Sprintf(formatter.fmtspec, record.Line)...)
+	return formatter.spec.AppendInt(buf, int64(record.Line))
 }
@@ -0,0 +1,28 @@
+package text
+
+import (
+	"fmt"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+type markFormatter struct {
+	text string
+}
+
+func newMarkFormatter(property, fmtspec string) ElementFormatter {
+	if property == "" {
+		property = "MARKED"
+	}
+	if fmtspec == "" {
+		fmtspec = "%s"
+	}
+	return &markFormatter{text: fmt.Sprintf(fmtspec, property)}
+}
+
+func (formatter *markFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
+	if record.Aux.Marked {
+		return append(buf, formatter.text...)
+	}
+	return buf
+}
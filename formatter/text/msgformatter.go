@@ -1,26 +1,20 @@
 package text
 
 import (
-	"fmt"
-
 	"github.com/fufuok/gxlog/iface"
 )
 
 type msgFormatter struct {
-	property string
-	fmtspec  string
+	spec fmtSpec
 }
 
-func newMsgFormatter(property, fmtspec string) elementFormatter {
+func newMsgFormatter(_, fmtspec string) ElementFormatter {
 	if fmtspec == "" {
 		fmtspec = "%s"
 	}
-	return &msgFormatter{property: property, fmtspec: fmtspec}
+	return &msgFormatter{spec: newFmtSpec(fmtspec)}
 }
 
 func (formatter *msgFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
-	if formatter.fmtspec == "%s" {
-		return append(buf, record.Msg...)
-	}
-	return append(buf, fmt.Sprintf(formatter.fmtspec, record.Msg)...)
+	return formatter.spec.AppendString(buf, record.Msg)
 }
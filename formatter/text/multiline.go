@@ -0,0 +1,90 @@
+package text
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+type msgProcessor struct {
+	multiLine  MultiLineMode
+	linePrefix string
+	maxMsgSize int
+	ellipsis   string
+}
+
+// Truncate returns the record as it is if its Msg is NOT longer than the max
+// size. Otherwise, it returns a copy of the record with the Msg truncated at a
+// rune boundary and the ellipsis appended. The Msg is truncated before it is
+// formatted, so that the padding of the fmtspec of the msg does NOT count.
+func (proc *msgProcessor) Truncate(record *iface.Record) *iface.Record {
+	msg := record.Msg
+	if proc.maxMsgSize <= 0 || len(msg) <= proc.maxMsgSize {
+		return record
+	}
+	end := proc.maxMsgSize
+	for end > 0 && !utf8.RuneStart(msg[end]) {
+		end--
+	}
+	truncated := *record
+	truncated.Msg = msg[:end] + proc.ellipsis
+	return &truncated
+}
+
+// Process handles the continuation lines of the message in buf[begin:]. The
+// buf[lineStart:headerEnd] is the header of the log.
+func (proc *msgProcessor) Process(buf []byte, lineStart, headerEnd, begin int) []byte {
+	switch proc.multiLine {
+	case MultiLinePrefix:
+		return proc.prefixLines(buf, begin, []byte(proc.linePrefix))
+	case MultiLineHeader:
+		return proc.prefixLines(buf, begin, buf[lineStart:headerEnd])
+	case MultiLineEscape:
+		return proc.escapeLines(buf, begin)
+	}
+	return buf
+}
+
+// prefixLines inserts the prefix after each newline in buf[begin:] except for
+// a trailing one.
+func (proc *msgProcessor) prefixLines(buf []byte, begin int, prefix []byte) []byte {
+	msg := buf[begin:]
+	if len(msg) == 0 || bytes.IndexByte(msg[:len(msg)-1], '\n') < 0 {
+		return buf
+	}
+	msg = append([]byte(nil), msg...)
+	prefix = append([]byte(nil), prefix...)
+	buf = buf[:begin]
+	for {
+		i := bytes.IndexByte(msg, '\n')
+		if i < 0 || i == len(msg)-1 {
+			return append(buf, msg...)
+		}
+		buf = append(buf, msg[:i+1]...)
+		buf = append(buf, prefix...)
+		msg = msg[i+1:]
+	}
+}
+
+// escapeLines escapes each newline and carriage return in buf[begin:] as "\n"
+// and "\r".
+func (proc *msgProcessor) escapeLines(buf []byte, begin int) []byte {
+	msg := buf[begin:]
+	if bytes.IndexByte(msg, '\n') < 0 && bytes.IndexByte(msg, '\r') < 0 {
+		return buf
+	}
+	msg = append([]byte(nil), msg...)
+	buf = buf[:begin]
+	for _, c := range msg {
+		switch c {
+		case '\n':
+			buf = append(buf, `\n`...)
+		case '\r':
+			buf = append(buf, `\r`...)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
@@ -1,7 +1,6 @@
 package text
 
 import (
-	"fmt"
 	"strconv"
 
 	"github.com/fufuok/gxlog/formatter/internal/util"
@@ -10,24 +9,21 @@ import (
 
 type pkgFormatter struct {
 	segments int
-	fmtspec  string
+	spec     fmtSpec
 }
 
-func newPkgFormatter(property, fmtspec string) elementFormatter {
+func newPkgFormatter(property, fmtspec string) ElementFormatter {
 	if fmtspec == "" {
 		fmtspec = "%s"
 	}
 	segments, _ := strconv.Atoi(property)
 	return &pkgFormatter{
 		segments: segments,
-		fmtspec:  fmtspec,
+		spec:     newFmtSpec(fmtspec),
 	}
 }
 
 func (formatter *pkgFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
 	pkg := util.LastSegments(record.Pkg, formatter.segments, '/')
-	if formatter.fmtspec == "%s" {
-		return append(buf, pkg...)
-	}
-	return append(buf, fmt.Sprintf(formatter.fmtspec, pkg)...)
+	return formatter.spec.AppendString(buf, pkg)
 }
@@ -1,26 +1,20 @@
 package text
 
 import (
-	"fmt"
-
 	"github.com/fufuok/gxlog/iface"
 )
 
 type prefixFormatter struct {
-	property string
-	fmtspec  string
+	spec fmtSpec
 }
 
-func newPrefixFormatter(property, fmtspec string) elementFormatter {
+func newPrefixFormatter(_, fmtspec string) ElementFormatter {
 	if fmtspec == "" {
 		fmtspec = "%s"
 	}
-	return &prefixFormatter{property: property, fmtspec: fmtspec}
+	return &prefixFormatter{spec: newFmtSpec(fmtspec)}
 }
 
 func (formatter *prefixFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
-	if formatter.fmtspec == "%s" {
-		return append(buf, record.Aux.Prefix...)
-	}
-	return append(buf, fmt.Sprintf(formatter.fmtspec, record.Aux.Prefix)...)
+	return formatter.spec.AppendString(buf, record.Aux.Prefix)
 }
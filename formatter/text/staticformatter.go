@@ -0,0 +1,53 @@
+package text
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+// staticFormatter formats a value that does NOT vary with logs. The value is
+// formatted once when the formatter is created.
+type staticFormatter struct {
+	text string
+}
+
+func newStaticFormatter(value interface{}, fmtspec string) ElementFormatter {
+	return &staticFormatter{text: fmt.Sprintf(fmtspec, value)}
+}
+
+func (formatter *staticFormatter) FormatElement(buf []byte, _ *iface.Record) []byte {
+	return append(buf, formatter.text...)
+}
+
+func newHostFormatter(_, fmtspec string) ElementFormatter {
+	if fmtspec == "" {
+		fmtspec = "%s"
+	}
+	host, _ := os.Hostname()
+	return newStaticFormatter(host, fmtspec)
+}
+
+func newPidFormatter(_, fmtspec string) ElementFormatter {
+	if fmtspec == "" {
+		return &staticFormatter{text: strconv.Itoa(os.Getpid())}
+	}
+	return newStaticFormatter(os.Getpid(), fmtspec)
+}
+
+func newAppFormatter(_, fmtspec string) ElementFormatter {
+	if fmtspec == "" {
+		fmtspec = "%s"
+	}
+	return newStaticFormatter(filepath.Base(os.Args[0]), fmtspec)
+}
+
+func newEnvFormatter(property, fmtspec string) ElementFormatter {
+	if fmtspec == "" {
+		fmtspec = "%s"
+	}
+	return newStaticFormatter(os.Getenv(property), fmtspec)
+}
@@ -0,0 +1,113 @@
+package text
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fufuok/gxlog/formatter/internal/util"
+	"github.com/fufuok/gxlog/iface"
+)
+
+var colorNames = map[string]Color{
+	"black":   Black,
+	"red":     Red,
+	"green":   Green,
+	"yellow":  Yellow,
+	"blue":    Blue,
+	"magenta": Magenta,
+	"cyan":    Cyan,
+	"white":   White,
+}
+
+var attrCodes = map[string]string{
+	"bold":      util.BoldCode,
+	"dim":       util.DimCode,
+	"italic":    util.ItalicCode,
+	"underline": util.UnderlineCode,
+	"blink":     util.BlinkCode,
+	"reverse":   util.ReverseCode,
+}
+
+// A style is the colors and attributes of an element.
+type style struct {
+	codes    string // SGR parameters except for the level color
+	useLevel bool   // whether to use the color of the level as the foreground
+}
+
+// parseStyle parses a style, which is a list of tokens separated by commas.
+// A token is one of the follows:
+//
+//	level                  the color of the level, or the marked color
+//	<color>                a foreground color
+//	bg-<color>             a background color
+//	bold, dim, italic, underline, blink, reverse
+//
+// where the color is a basic color name (e.g. red), a bright one (e.g.
+// bright-red), an index of the 256-color palette (e.g. 208) or a 24-bit color
+// in hex (e.g. #ff8800).
+// It returns nil if the str is empty.
+func parseStyle(str string) (*style, error) {
+	if str == "" {
+		return nil, nil
+	}
+	sty := &style{}
+	var codes []string
+	for _, token := range strings.Split(strings.ToLower(str), ",") {
+		token = strings.TrimSpace(token)
+		if token == "level" {
+			sty.useLevel = true
+			continue
+		}
+		if code, ok := attrCodes[token]; ok {
+			codes = append(codes, code)
+			continue
+		}
+		bg := strings.HasPrefix(token, "bg-")
+		if bg {
+			token = token[3:]
+		}
+		color, err := parseColor(token)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, color.code(bg))
+	}
+	sty.codes = strings.Join(codes, ";")
+	return sty, nil
+}
+
+func parseColor(str string) (Color, error) {
+	if strings.HasPrefix(str, "#") {
+		rgb, err := strconv.ParseUint(str[1:], 16, 32)
+		if err != nil || len(str) != 7 {
+			return 0, fmt.Errorf("invalid 24-bit color %q", str)
+		}
+		return TrueColor(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb)), nil
+	}
+	if index, err := strconv.ParseUint(str, 10, 8); err == nil {
+		return Color256(uint8(index)), nil
+	}
+	name := strings.TrimPrefix(str, "bright-")
+	color, ok := colorNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown style %q", str)
+	}
+	if name != str {
+		color += BrightBlack - Black
+	}
+	return color, nil
+}
+
+// appendLeft appends the escape sequence that begins the style.
+func (sty *style) appendLeft(buf []byte, mgr *colorMgr, record *iface.Record) []byte {
+	buf = append(buf, "\033["...)
+	buf = append(buf, sty.codes...)
+	if sty.useLevel {
+		if sty.codes != "" {
+			buf = append(buf, ';')
+		}
+		buf = append(buf, mgr.ColorCode(record.Level, record.Aux.Marked)...)
+	}
+	return append(buf, 'm')
+}
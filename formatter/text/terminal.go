@@ -0,0 +1,38 @@
+package text
+
+import (
+	"io"
+	"os"
+)
+
+// IsTerminal returns whether the w is a terminal. The w is a terminal only if
+// it has a method Fd (e.g. an *os.File) and the file descriptor refers to a
+// terminal. It always returns false on systems other than Linux and BSDs.
+func IsTerminal(w io.Writer) bool {
+	file, ok := w.(interface{ Fd() uintptr })
+	if !ok {
+		return false
+	}
+	return isTerminal(file.Fd())
+}
+
+// ColorSupported returns whether logs written to the w should be colored.
+// It is decided by the environment variables and the w as the follows:
+//   - false if NO_COLOR is set and not empty
+//   - true if FORCE_COLOR is set and not one of "", "0" and "false"
+//   - false if TERM is "dumb"
+//   - IsTerminal(w) otherwise
+func ColorSupported(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	switch os.Getenv("FORCE_COLOR") {
+	case "", "0", "false":
+	default:
+		return true
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return IsTerminal(w)
+}
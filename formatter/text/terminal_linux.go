@@ -0,0 +1,13 @@
+package text
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func isTerminal(fd uintptr) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS,
+		uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
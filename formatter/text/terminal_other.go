@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package text
+
+func isTerminal(fd uintptr) bool {
+	return false
+}
@@ -1,7 +1,6 @@
 package text
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/fufuok/gxlog/iface"
@@ -17,26 +16,24 @@ const (
 )
 
 type timeFormatter struct {
-	layout  string
-	fmtspec string
+	layout string
+	spec   fmtSpec
 }
 
-func newTimeFormatter(property, fmtspec string) elementFormatter {
+func newTimeFormatter(property, fmtspec string) ElementFormatter {
 	if fmtspec == "" {
 		fmtspec = "%s"
 	}
 	return &timeFormatter{
-		layout:  makeTimeLayout(property),
-		fmtspec: fmtspec,
+		layout: makeTimeLayout(property),
+		spec:   newFmtSpec(fmtspec),
 	}
 }
 
 func (formatter *timeFormatter) FormatElement(buf []byte, record *iface.Record) []byte {
-	if formatter.fmtspec == "%s" {
-		return record.Time.AppendFormat(buf, formatter.layout)
-	}
-	timeStr := record.Time.Format(formatter.layout)
-	return append(buf, fmt.Sprintf(formatter.fmtspec, timeStr)...)
+	begin := len(buf)
+	buf = record.Time.AppendFormat(buf, formatter.layout)
+	return formatter.spec.FormatBytes(buf, begin)
 }
 
 func makeTimeLayout(property string) string {
@@ -35,6 +35,9 @@ type Auxiliary struct {
 }
 
 // A Record contains all information of a log.
+// The GID is the id of the goroutine that emits the log, it is 0 if it is
+// NOT captured. A Logger captures it only if it is used by the Formatter of
+// a slot to which the log goes, see GoroutineIDUser.
 type Record struct {
 	Time  time.Time
 	Level Level
@@ -42,6 +45,7 @@ type Record struct {
 	Line  int
 	Pkg   string
 	Func  string
+	GID   int64
 	Msg   string
 	Aux   Auxiliary
 }
@@ -49,14 +53,43 @@ type Record struct {
 // Formatter is the interface that a formatter of a Logger needs to implement.
 // A Formatter must NOT modify the record. In case of asynchrony, a Formatter
 // needs to make and return a new byte slice each time.
+// Format is called by multiple goroutines concurrently, so a Formatter MUST be
+// concurrency safe.
 //
 // Do NOT call any method of the Logger within Format, or it may deadlock.
 type Formatter interface {
 	Format(record *Record) []byte
 }
 
+// Releaser is the interface that a Formatter implements if the byte slices
+// it returns may come from a pool. Release is called with a byte slice returned
+// by Format after all writers return from Write, so that the byte slice can be
+// reused. A Writer that retains the byte slice after Write returns needs to
+// copy it if such a Formatter is linked with it. The builtin writers copy the
+// byte slices they retain.
+//
+// Only the Logger calls Release. The byte slices returned by a Formatter that is
+// called directly are never released and are left to the garbage collector.
+// Whether a byte slice comes from a pool MUST be decided by Format rather than
+// Release, since the settings of a Formatter may change in between.
+//
+// Do NOT use the byte slice after it is released.
+type Releaser interface {
+	Release(bs []byte)
+}
+
+// GoroutineIDUser is the interface that a Formatter implements if it uses the
+// GID of a Record. Getting the id of a goroutine is costly, so a Logger gets it
+// only if UsesGoroutineID of such a Formatter returns true. The GID is 0 for
+// a Formatter that does NOT implement it.
+type GoroutineIDUser interface {
+	UsesGoroutineID() bool
+}
+
 // Writer is the interface that a writer of a Logger needs to implement.
 // A Writer must NOT modify the bs and record.
+// Write is called by multiple goroutines concurrently, so a Writer MUST handle
+// its own synchronization.
 //
 // Do NOT call any method of the Logger within Write, or it may deadlock.
 type Writer interface {
@@ -94,13 +94,12 @@ func (log *Logger) WithCountLimit(batch, limit int64) *Logger {
 		panic("logger.WithCountLimit: negative limit")
 	}
 	clone := *log
+	counters := log.counters
 	clone.attr.CountLimiter = func(record *iface.Record) bool {
-		loc := locator{
+		n := counters.Inc(locator{
 			File: record.File,
 			Line: record.Line,
-		}
-		n := log.countMap[loc]
-		log.countMap[loc]++
+		})
 		return n%batch < limit
 	}
 	return &clone
@@ -126,17 +125,13 @@ func (log *Logger) WithTimeLimit(duration time.Duration, limit int) *Logger {
 		panic("logger.WithTimeLimit: negative limit")
 	}
 	clone := *log
+	queues := log.queues
 	clone.attr.TimeLimiter = func(record *iface.Record) bool {
 		loc := locator{
 			File: record.File,
 			Line: record.Line,
 		}
-		queue := log.timeMap[loc]
-		if queue == nil {
-			queue = newTimeQueue(duration, limit)
-			log.timeMap[loc] = queue
-		}
-		return queue.Enqueue(record.Time)
+		return queues.Enqueue(loc, record.Time, duration, limit)
 	}
 	return &clone
 }
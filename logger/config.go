@@ -16,6 +16,7 @@ const (
 	LimitByCount
 	LimitByTime
 	Runtime
+	GoroutineID
 )
 
 // The Filter type defines a function type which is used to filter logs.
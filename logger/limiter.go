@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shardCount is the count of shards of a countMap or timeQueueMap. Logs from
+// different locations mostly fall into different shards, so that goroutines
+// emitting them do not contend for the same lock.
+const shardCount = 32
+
+func (loc locator) shard() int {
+	// FNV-1a
+	hash := uint32(2166136261)
+	for i := 0; i < len(loc.File); i++ {
+		hash ^= uint32(loc.File[i])
+		hash *= 16777619
+	}
+	hash ^= uint32(loc.Line)
+	hash *= 16777619
+	return int(hash % shardCount)
+}
+
+// A countMap keeps the count of logs of each location. It is concurrency safe.
+type countMap struct {
+	shards [shardCount]countShard
+}
+
+type countShard struct {
+	counters map[locator]*int64
+	lock     sync.RWMutex
+}
+
+func newCountMap() *countMap {
+	cm := &countMap{}
+	for i := range cm.shards {
+		cm.shards[i].counters = make(map[locator]*int64, mapInitCap/shardCount)
+	}
+	return cm
+}
+
+// Inc increases the count of the loc by one and returns the count before.
+func (cm *countMap) Inc(loc locator) int64 {
+	shard := &cm.shards[loc.shard()]
+	shard.lock.RLock()
+	counter := shard.counters[loc]
+	shard.lock.RUnlock()
+	if counter == nil {
+		shard.lock.Lock()
+		counter = shard.counters[loc]
+		if counter == nil {
+			counter = new(int64)
+			shard.counters[loc] = counter
+		}
+		shard.lock.Unlock()
+	}
+	return atomic.AddInt64(counter, 1) - 1
+}
+
+// A timeQueueMap keeps the timeQueue of each location. It is concurrency safe.
+type timeQueueMap struct {
+	shards [shardCount]timeQueueShard
+}
+
+type timeQueueShard struct {
+	queues map[locator]*lockedTimeQueue
+	lock   sync.RWMutex
+}
+
+type lockedTimeQueue struct {
+	queue *timeQueue
+	lock  sync.Mutex
+}
+
+func newTimeQueueMap() *timeQueueMap {
+	tm := &timeQueueMap{}
+	for i := range tm.shards {
+		tm.shards[i].queues = make(map[locator]*lockedTimeQueue, mapInitCap/shardCount)
+	}
+	return tm
+}
+
+// Enqueue enqueues the clock to the timeQueue of the loc and returns whether it
+// succeeds. If the loc has no timeQueue yet, one is created with the duration
+// and limit.
+func (tm *timeQueueMap) Enqueue(loc locator, clock time.Time,
+	duration time.Duration, limit int) bool {
+
+	shard := &tm.shards[loc.shard()]
+	shard.lock.RLock()
+	locked := shard.queues[loc]
+	shard.lock.RUnlock()
+	if locked == nil {
+		shard.lock.Lock()
+		locked = shard.queues[loc]
+		if locked == nil {
+			locked = &lockedTimeQueue{queue: newTimeQueue(duration, limit)}
+			shard.queues[loc] = locked
+		}
+		shard.lock.Unlock()
+	}
+	locked.lock.Lock()
+	defer locked.lock.Unlock()
+
+	return locked.queue.Enqueue(clock)
+}
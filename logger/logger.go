@@ -4,14 +4,17 @@
 package logger
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fufuok/gxlog/iface"
@@ -24,30 +27,47 @@ const callDepthOffset = 3
 // Slot has its independent level and filter. Logger calls the Formatter and
 // Writer of each Slot in the order from Slot0 to Slot7 when a log is emitted.
 //
-// All methods of A Logger are concurrency safe.
+// All methods of A Logger are concurrency safe. Logs are emitted without any
+// global lock: the Config and slots are read from a snapshot that is replaced
+// atomically when they are updated, and the Formatters and Writers are called
+// by multiple goroutines concurrently. So each Formatter and Writer MUST handle
+// its own synchronization.
 // A Logger MUST be created with New.
 type Logger struct {
-	config *Config
-	slots  []slotLink
+	// *snapshot, shared with the clones of the Logger
+	snapshot *atomic.Value
+	counters *countMap
+	queues   *timeQueueMap
+	attr     copyOnWrite
+	// serializes the updates of the snapshot
+	lock *sync.Mutex
+}
+
+// A snapshot holds the Config and slots of a Logger. It is never modified after
+// it is stored, an update stores a modified copy instead.
+type snapshot struct {
+	config Config
+	slots  [MaxSlot]slotLink
 	// store indexes of equivalent formatters, used to avoid redundant formatting
-	equivalents [][]int
-	countMap    map[locator]int64
-	timeMap     map[locator]*timeQueue
-	attr        copyOnWrite
-	lock        *sync.Mutex
+	equivalents [MaxSlot][]int
+	// store formatters that may use the GID, used to avoid getting it in vain
+	gidUsers [MaxSlot]iface.GoroutineIDUser
 }
 
 // New creates a new Logger with the config.
 func New(config Config) *Logger {
 	config.setDefaults()
+	snap := &snapshot{config: config}
+	for slot := range snap.slots {
+		snap.slots[slot] = nullSlotLink
+	}
 	logger := &Logger{
-		config:      &config,
-		equivalents: make([][]int, MaxSlot),
-		countMap:    make(map[locator]int64, mapInitCap),
-		timeMap:     make(map[locator]*timeQueue, mapInitCap),
-		lock:        new(sync.Mutex),
+		snapshot: new(atomic.Value),
+		counters: newCountMap(),
+		queues:   newTimeQueueMap(),
+		lock:     new(sync.Mutex),
 	}
-	logger.initSlots()
+	logger.snapshot.Store(snap)
 	return logger
 }
 
@@ -242,24 +262,29 @@ func (log *Logger) Timingf(fmtstr string, args ...interface{}) func() {
 }
 
 func (log *Logger) levels() (iface.Level, iface.Level, iface.Level) {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.config.Level, log.config.TrackLevel, log.config.ExitLevel
+	config := &log.load().config
+	return config.Level, config.TrackLevel, config.ExitLevel
 }
 
 func (log *Logger) timingLevel() (iface.Level, iface.Level) {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.config.Level, log.config.TimingLevel
+	config := &log.load().config
+	return config.Level, config.TimingLevel
 }
 
 func (log *Logger) panicLevel() (iface.Level, iface.Level) {
-	log.lock.Lock()
-	defer log.lock.Unlock()
+	config := &log.load().config
+	return config.Level, config.PanicLevel
+}
+
+func (log *Logger) load() *snapshot {
+	return log.snapshot.Load().(*snapshot)
+}
 
-	return log.config.Level, log.config.PanicLevel
+// clone returns a copy of the current snapshot to be modified and stored.
+// It MUST be called with the lock held.
+func (log *Logger) clone() *snapshot {
+	snap := *log.load()
+	return &snap
 }
 
 func (log *Logger) write(callDepth int, level iface.Level, msg string) {
@@ -267,13 +292,17 @@ func (log *Logger) write(callDepth int, level iface.Level, msg string) {
 		panic("logger: invalid level")
 	}
 
+	snap := log.load()
+	config := &snap.config
+
 	file, line, pkg, fn := "", 0, "", ""
-	if log.config.Disabled&Runtime == 0 {
+	if config.Disabled&Runtime == 0 {
 		file, line, pkg, fn = getPosInfo(callDepth + callDepthOffset)
 	}
-
-	log.lock.Lock()
-	defer log.lock.Unlock()
+	var gid int64
+	if config.Disabled&GoroutineID == 0 && snap.usesGID(level) {
+		gid = getGoroutineID()
+	}
 
 	record := &iface.Record{
 		Time:  time.Now(),
@@ -282,18 +311,20 @@ func (log *Logger) write(callDepth int, level iface.Level, msg string) {
 		Line:  line,
 		Pkg:   pkg,
 		Func:  fn,
+		GID:   gid,
 		Msg:   msg,
 	}
 
-	if !log.filter(record) {
+	if !log.filter(config, record) {
 		return
 	}
 
-	log.attachAux(record)
+	log.attachAux(config, record)
 
 	var formats [MaxSlot][]byte
+	var releasers [MaxSlot]iface.Releaser
 	for slot := 0; slot < MaxSlot; slot++ {
-		link := &log.slots[slot]
+		link := &snap.slots[slot]
 		if link.Level > level {
 			continue
 		}
@@ -303,24 +334,31 @@ func (log *Logger) write(callDepth int, level iface.Level, msg string) {
 		format := formats[slot]
 		if format == nil {
 			format = link.Formatter.Format(record)
-			for _, id := range log.equivalents[slot] {
+			for _, id := range snap.equivalents[slot] {
 				formats[id] = format
 			}
+			formats[slot] = format
+			releasers[slot], _ = link.Formatter.(iface.Releaser)
 		}
 		link.Writer.Write(format, record)
 	}
+	for slot, releaser := range releasers {
+		if releaser != nil {
+			releaser.Release(formats[slot])
+		}
+	}
 }
 
-func (log *Logger) filter(record *iface.Record) bool {
-	if log.config.Filter != nil && !log.config.Filter(record) {
+func (log *Logger) filter(config *Config, record *iface.Record) bool {
+	if config.Filter != nil && !config.Filter(record) {
 		return false
 	}
-	if log.config.Disabled&LimitByCount == 0 {
+	if config.Disabled&LimitByCount == 0 {
 		if log.attr.CountLimiter != nil && !log.attr.CountLimiter(record) {
 			return false
 		}
 	}
-	if log.config.Disabled&LimitByTime == 0 {
+	if config.Disabled&LimitByTime == 0 {
 		if log.attr.TimeLimiter != nil && !log.attr.TimeLimiter(record) {
 			return false
 		}
@@ -328,14 +366,14 @@ func (log *Logger) filter(record *iface.Record) bool {
 	return true
 }
 
-func (log *Logger) attachAux(record *iface.Record) {
-	if log.config.Disabled&Prefix == 0 {
+func (log *Logger) attachAux(config *Config, record *iface.Record) {
+	if config.Disabled&Prefix == 0 {
 		record.Aux.Prefix = log.attr.Prefix
 	}
-	if log.config.Disabled&StaticContext == 0 {
+	if config.Disabled&StaticContext == 0 {
 		record.Aux.Contexts = log.attr.Contexts
 	}
-	if log.config.Disabled&DynamicContext == 0 {
+	if config.Disabled&DynamicContext == 0 {
 		for _, context := range log.attr.DynamicContexts {
 			record.Aux.Contexts = append(record.Aux.Contexts, iface.Context{
 				Key:   fmt.Sprint(context.Key),
@@ -343,7 +381,7 @@ func (log *Logger) attachAux(record *iface.Record) {
 			})
 		}
 	}
-	if log.config.Disabled&Mark == 0 {
+	if config.Disabled&Mark == 0 {
 		record.Aux.Marked = log.attr.Marked
 	}
 }
@@ -375,6 +413,19 @@ func getPosInfo(callDepth int) (file string, line int, pkg, fn string) {
 	return filepath.ToSlash(file), line, pkg, fn
 }
 
+// getGoroutineID parses the id of the current goroutine from the first line of
+// its stack, e.g. "goroutine 18 [running]:".
+func getGoroutineID() int64 {
+	var buf [64]byte
+	bs := buf[:runtime.Stack(buf[:], false)]
+	bs = bytes.TrimPrefix(bs, []byte("goroutine "))
+	if i := bytes.IndexByte(bs, ' '); i > 0 {
+		bs = bs[:i]
+	}
+	id, _ := strconv.ParseInt(string(bs), 10, 64)
+	return id
+}
+
 func splitPkgAndFunc(name string) (string, string) {
 	lastSlash := strings.LastIndexByte(name, '/')
 	nextDot := strings.IndexByte(name[lastSlash+1:], '.')
@@ -0,0 +1,222 @@
+package logger_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/formatter"
+	"github.com/fufuok/gxlog/formatter/text"
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/logger"
+	"github.com/fufuok/gxlog/writer"
+)
+
+const (
+	testGoroutines = 8
+	testLogs       = 1000
+)
+
+type countFormatter struct {
+	formats  int64
+	releases int64
+}
+
+func (cf *countFormatter) Format(record *iface.Record) []byte {
+	atomic.AddInt64(&cf.formats, 1)
+	return []byte(record.Msg)
+}
+
+func (cf *countFormatter) Release(bs []byte) {
+	atomic.AddInt64(&cf.releases, 1)
+}
+
+func TestConcurrentWrite(t *testing.T) {
+	log := logger.New(logger.Config{})
+	var count int64
+	log.Link(logger.Slot0, formatter.Func(func(record *iface.Record) []byte {
+		return []byte(record.Msg)
+	}), writer.Func(func(bs []byte, record *iface.Record) {
+		atomic.AddInt64(&count, 1)
+	}))
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			log.SetSlotLevel(logger.Slot1, iface.Level(i%int(iface.Off)+1))
+			log.CopySlot(logger.Slot2, logger.Slot1)
+			log.SetDisabled(logger.Mark)
+			log.UpdateConfig(func(config logger.Config) logger.Config {
+				config.TimingLevel = iface.Debug
+				return config
+			})
+		}
+	}()
+	run(func() {
+		log.WithPrefix("** ").WithContext("k", "v").Info("testing")
+	})
+	close(done)
+	wg.Wait()
+
+	if count != testGoroutines*testLogs {
+		t.Errorf("TestConcurrentWrite: got %d logs, expect %d",
+			count, testGoroutines*testLogs)
+	}
+}
+
+func TestEquivalents(t *testing.T) {
+	log := logger.New(logger.Config{})
+	cf := &countFormatter{}
+	var count int64
+	counter := writer.Func(func(bs []byte, record *iface.Record) {
+		atomic.AddInt64(&count, 1)
+	})
+	log.Link(logger.Slot0, cf, counter)
+	log.Link(logger.Slot3, cf, counter)
+	run(func() {
+		log.Info("testing")
+	})
+
+	total := int64(testGoroutines * testLogs)
+	if count != 2*total {
+		t.Errorf("TestEquivalents: got %d writes, expect %d", count, 2*total)
+	}
+	if cf.formats != total || cf.releases != total {
+		t.Errorf("TestEquivalents: got %d formats and %d releases, expect %d",
+			cf.formats, cf.releases, total)
+	}
+}
+
+func TestCountLimit(t *testing.T) {
+	log := logger.New(logger.Config{})
+	var count int64
+	log.Link(logger.Slot0, formatter.Null(), writer.Func(
+		func(bs []byte, record *iface.Record) {
+			atomic.AddInt64(&count, 1)
+		}))
+	limited := log.WithCountLimit(10, 3)
+	run(func() {
+		limited.Info("testing")
+	})
+
+	if expect := int64(testGoroutines * testLogs * 3 / 10); count != expect {
+		t.Errorf("TestCountLimit: got %d logs, expect %d", count, expect)
+	}
+}
+
+func TestTimeLimit(t *testing.T) {
+	log := logger.New(logger.Config{})
+	var count int64
+	log.Link(logger.Slot0, formatter.Null(), writer.Func(
+		func(bs []byte, record *iface.Record) {
+			atomic.AddInt64(&count, 1)
+		}))
+	limited := log.WithTimeLimit(time.Hour, 5)
+	run(func() {
+		limited.Info("testing")
+	})
+
+	if count != 5 {
+		t.Errorf("TestTimeLimit: got %d logs, expect 5", count)
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	log := logger.New(logger.Config{})
+	var gid int64
+	log.Link(logger.Slot1, formatter.Func(func(record *iface.Record) []byte {
+		gid = record.GID
+		return nil
+	}), writer.Null())
+
+	// the default path must NOT get the id of the goroutine
+	log.Info("testing")
+	if gid != 0 {
+		t.Errorf("TestGoroutineID: the gid is got without any user, gid: %d", gid)
+	}
+
+	formatter := text.New(text.Config{Header: "{{gid}}"})
+	log.Link(logger.Slot0, formatter, writer.Null(), iface.Warn)
+	log.Info("testing")
+	if gid != 0 {
+		t.Errorf("TestGoroutineID: the gid is got for a lower level, gid: %d", gid)
+	}
+	log.Warn("testing")
+	if gid <= 0 {
+		t.Errorf("TestGoroutineID: the gid is NOT got, gid: %d", gid)
+	}
+	log.Disable(logger.GoroutineID)
+	log.Warn("testing")
+	if gid != 0 {
+		t.Errorf("TestGoroutineID: the gid is got while disabled, gid: %d", gid)
+	}
+	log.Enable(logger.GoroutineID)
+	formatter.SetHeader("{{msg}}")
+	log.Warn("testing")
+	if gid != 0 {
+		t.Errorf("TestGoroutineID: the gid is got after the header changes, gid: %d", gid)
+	}
+}
+
+// BenchmarkLogDefault guards the cost of a log with the default settings.
+func BenchmarkLogDefault(b *testing.B) {
+	log := logger.New(logger.Config{})
+	log.Link(logger.Slot0, text.New(text.Config{}), writer.Null())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		log.Info("testing")
+	}
+}
+
+func BenchmarkLogParallel(b *testing.B) {
+	log := logger.New(logger.Config{})
+	log.Link(logger.Slot0, text.New(text.Config{
+		Header:  text.CompactHeader,
+		Pooling: true,
+	}), writer.Null())
+	benchmarkParallel(b, log)
+}
+
+func BenchmarkCountLimitParallel(b *testing.B) {
+	log := logger.New(logger.Config{})
+	log.Link(logger.Slot0, text.New(text.Config{
+		Header:  text.CompactHeader,
+		Pooling: true,
+	}), writer.Null())
+	benchmarkParallel(b, log.WithCountLimit(10, 3))
+}
+
+func benchmarkParallel(b *testing.B, log *logger.Logger) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			log.Info("testing")
+		}
+	})
+}
+
+// run calls the fn in testGoroutines goroutines, testLogs times each.
+func run(fn func()) {
+	var wg sync.WaitGroup
+	for i := 0; i < testGoroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < testLogs; j++ {
+				fn()
+			}
+		}()
+	}
+	wg.Wait()
+}
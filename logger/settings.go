@@ -6,10 +6,7 @@ import (
 
 // Config returns the Config of the Logger.
 func (log *Logger) Config() Config {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return *log.config
+	return log.load().config
 }
 
 // SetConfig sets the config to the Logger.
@@ -18,7 +15,9 @@ func (log *Logger) SetConfig(config Config) {
 	defer log.lock.Unlock()
 
 	config.setDefaults()
-	log.config = &config
+	snap := log.clone()
+	snap.config = config
+	log.snapshot.Store(snap)
 }
 
 // UpdateConfig calls the fn with the Config of the Logger, and then sets the
@@ -29,16 +28,14 @@ func (log *Logger) UpdateConfig(fn func(Config) Config) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	config := fn(*log.config)
-	log.config = &config
+	snap := log.clone()
+	snap.config = fn(snap.config)
+	log.snapshot.Store(snap)
 }
 
 // Level returns the level of the Logger.
 func (log *Logger) Level() iface.Level {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.config.Level
+	return log.load().config.Level
 }
 
 // SetLevel sets the level of the Logger.
@@ -46,15 +43,14 @@ func (log *Logger) SetLevel(level iface.Level) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.config.Level = level
+	snap := log.clone()
+	snap.config.Level = level
+	log.snapshot.Store(snap)
 }
 
 // TrackLevel returns the track level of the Logger.
 func (log *Logger) TrackLevel() iface.Level {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.config.TrackLevel
+	return log.load().config.TrackLevel
 }
 
 // SetTrackLevel sets the track level of the Logger.
@@ -62,15 +58,14 @@ func (log *Logger) SetTrackLevel(level iface.Level) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.config.TrackLevel = level
+	snap := log.clone()
+	snap.config.TrackLevel = level
+	log.snapshot.Store(snap)
 }
 
 // ExitLevel returns the exit level of the Logger.
 func (log *Logger) ExitLevel() iface.Level {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.config.ExitLevel
+	return log.load().config.ExitLevel
 }
 
 // SetExitLevel sets the exit level of the Logger.
@@ -78,15 +73,14 @@ func (log *Logger) SetExitLevel(level iface.Level) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.config.ExitLevel = level
+	snap := log.clone()
+	snap.config.ExitLevel = level
+	log.snapshot.Store(snap)
 }
 
 // TimingLevel returns the timing level of the Logger.
 func (log *Logger) TimingLevel() iface.Level {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.config.TimingLevel
+	return log.load().config.TimingLevel
 }
 
 // SetTimingLevel sets the timing level of the Logger.
@@ -94,15 +88,14 @@ func (log *Logger) SetTimingLevel(level iface.Level) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.config.TimingLevel = level
+	snap := log.clone()
+	snap.config.TimingLevel = level
+	log.snapshot.Store(snap)
 }
 
 // PanicLevel returns the panic level of the Logger.
 func (log *Logger) PanicLevel() iface.Level {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.config.PanicLevel
+	return log.load().config.PanicLevel
 }
 
 // SetPanicLevel sets the panic level of the Logger.
@@ -110,15 +103,14 @@ func (log *Logger) SetPanicLevel(level iface.Level) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.config.PanicLevel = level
+	snap := log.clone()
+	snap.config.PanicLevel = level
+	log.snapshot.Store(snap)
 }
 
 // Filter returns the filter of the Logger.
 func (log *Logger) Filter() Filter {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.config.Filter
+	return log.load().config.Filter
 }
 
 // SetFilter sets the filter of the Logger.
@@ -126,15 +118,14 @@ func (log *Logger) SetFilter(filter Filter) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.config.Filter = filter
+	snap := log.clone()
+	snap.config.Filter = filter
+	log.snapshot.Store(snap)
 }
 
 // Disabled returns the disabled flags of the Logger.
 func (log *Logger) Disabled() Flag {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.config.Disabled
+	return log.load().config.Disabled
 }
 
 // SetDisabled sets the disabled flags of the Logger.
@@ -142,7 +133,9 @@ func (log *Logger) SetDisabled(flags Flag) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.config.Disabled = flags
+	snap := log.clone()
+	snap.config.Disabled = flags
+	log.snapshot.Store(snap)
 }
 
 // Enable enables the flags of the Logger.
@@ -150,7 +143,9 @@ func (log *Logger) Enable(flags Flag) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.config.Disabled &^= flags
+	snap := log.clone()
+	snap.config.Disabled &^= flags
+	log.snapshot.Store(snap)
 }
 
 // Disable disables the flags of the Logger.
@@ -158,5 +153,7 @@ func (log *Logger) Disable(flags Flag) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.config.Disabled |= flags
+	snap := log.clone()
+	snap.config.Disabled |= flags
+	log.snapshot.Store(snap)
 }
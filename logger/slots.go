@@ -72,8 +72,10 @@ func (log *Logger) Link(slot Slot, formatter iface.Formatter,
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[slot] = link
-	log.updateEquivalents()
+	snap := log.clone()
+	snap.slots[slot] = link
+	snap.updateFormatters()
+	log.snapshot.Store(snap)
 }
 
 // Unlink sets the Formatter, Writer and Filter of the slot to nil and
@@ -82,8 +84,10 @@ func (log *Logger) Unlink(slot Slot) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[slot] = nullSlotLink
-	log.updateEquivalents()
+	snap := log.clone()
+	snap.slots[slot] = nullSlotLink
+	snap.updateFormatters()
+	log.snapshot.Store(snap)
 }
 
 // UnlinkAll sets the Formatter, Writer and Filter of all slots to nil and
@@ -92,10 +96,12 @@ func (log *Logger) UnlinkAll() {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	for i := range log.slots {
-		log.slots[i] = nullSlotLink
+	snap := log.clone()
+	for i := range snap.slots {
+		snap.slots[i] = nullSlotLink
 	}
-	log.updateEquivalents()
+	snap.updateFormatters()
+	log.snapshot.Store(snap)
 }
 
 // CopySlot copies the Formatter, Writer, Level and Filter of Slot src
@@ -104,8 +110,10 @@ func (log *Logger) CopySlot(dst, src Slot) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[dst] = log.slots[src]
-	log.updateEquivalents()
+	snap := log.clone()
+	snap.slots[dst] = snap.slots[src]
+	snap.updateFormatters()
+	log.snapshot.Store(snap)
 }
 
 // MoveSlot copies the Formatter, Writer, Level and Filter of Slot from
@@ -114,9 +122,11 @@ func (log *Logger) MoveSlot(to, from Slot) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[to] = log.slots[from]
-	log.slots[from] = nullSlotLink
-	log.updateEquivalents()
+	snap := log.clone()
+	snap.slots[to] = snap.slots[from]
+	snap.slots[from] = nullSlotLink
+	snap.updateFormatters()
+	log.snapshot.Store(snap)
 }
 
 // SwapSlot swaps the Formatter, Writer, Level and Filter of the slots.
@@ -124,16 +134,15 @@ func (log *Logger) SwapSlot(left, right Slot) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[left], log.slots[right] = log.slots[right], log.slots[left]
-	log.updateEquivalents()
+	snap := log.clone()
+	snap.slots[left], snap.slots[right] = snap.slots[right], snap.slots[left]
+	snap.updateFormatters()
+	log.snapshot.Store(snap)
 }
 
 // SlotFormatter returns the Formatter of the slot.
 func (log *Logger) SlotFormatter(slot Slot) iface.Formatter {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.slots[slot].Formatter
+	return log.load().slots[slot].Formatter
 }
 
 // SetSlotFormatter sets the Formatter of the slot. The formatter must NOT be nil.
@@ -141,16 +150,15 @@ func (log *Logger) SetSlotFormatter(slot Slot, formatter iface.Formatter) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[slot].Formatter = formatter
-	log.updateEquivalents()
+	snap := log.clone()
+	snap.slots[slot].Formatter = formatter
+	snap.updateFormatters()
+	log.snapshot.Store(snap)
 }
 
 // SlotWriter returns the Writer of the slot.
 func (log *Logger) SlotWriter(slot Slot) iface.Writer {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.slots[slot].Writer
+	return log.load().slots[slot].Writer
 }
 
 // SetSlotWriter sets the Writer of the slot. The writer must NOT be nil.
@@ -158,15 +166,14 @@ func (log *Logger) SetSlotWriter(slot Slot, writer iface.Writer) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[slot].Writer = writer
+	snap := log.clone()
+	snap.slots[slot].Writer = writer
+	log.snapshot.Store(snap)
 }
 
 // SlotLevel returns the Level of the slot.
 func (log *Logger) SlotLevel(slot Slot) iface.Level {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.slots[slot].Level
+	return log.load().slots[slot].Level
 }
 
 // SetSlotLevel sets the Level of the slot.
@@ -174,15 +181,14 @@ func (log *Logger) SetSlotLevel(slot Slot, level iface.Level) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[slot].Level = level
+	snap := log.clone()
+	snap.slots[slot].Level = level
+	log.snapshot.Store(snap)
 }
 
 // SlotFilter returns the Filter of the slot.
 func (log *Logger) SlotFilter(slot Slot) Filter {
-	log.lock.Lock()
-	defer log.lock.Unlock()
-
-	return log.slots[slot].Filter
+	return log.load().slots[slot].Filter
 }
 
 // SetSlotFilter sets the Filter of the slot.
@@ -190,27 +196,38 @@ func (log *Logger) SetSlotFilter(slot Slot, filter Filter) {
 	log.lock.Lock()
 	defer log.lock.Unlock()
 
-	log.slots[slot].Filter = filter
-}
-
-func (log *Logger) initSlots() {
-	for slot := 0; slot < MaxSlot; slot++ {
-		log.slots = append(log.slots, nullSlotLink)
-	}
+	snap := log.clone()
+	snap.slots[slot].Filter = filter
+	log.snapshot.Store(snap)
 }
 
-func (log *Logger) updateEquivalents() {
+// updateFormatters rebuilds the equivalents and the gidUsers of the snapshot.
+// The slices are allocated anew since the old ones are shared with the previous
+// snapshot.
+func (snap *snapshot) updateFormatters() {
 	for i := 0; i < MaxSlot; i++ {
-		log.equivalents[i] = log.equivalents[i][:0]
-		if !reflect.TypeOf(log.slots[i].Formatter).Comparable() {
+		snap.gidUsers[i], _ = snap.slots[i].Formatter.(iface.GoroutineIDUser)
+		snap.equivalents[i] = nil
+		if !reflect.TypeOf(snap.slots[i].Formatter).Comparable() {
 			continue
 		}
 		for j := i + 1; j < MaxSlot; j++ {
-			if !reflect.TypeOf(log.slots[j].Formatter).Comparable() ||
-				log.slots[i].Formatter != log.slots[j].Formatter {
+			if !reflect.TypeOf(snap.slots[j].Formatter).Comparable() ||
+				snap.slots[i].Formatter != snap.slots[j].Formatter {
 				continue
 			}
-			log.equivalents[i] = append(log.equivalents[i], j)
+			snap.equivalents[i] = append(snap.equivalents[i], j)
+		}
+	}
+}
+
+// usesGID reports whether the Formatter of any slot to which a log of the level
+// goes uses the GID.
+func (snap *snapshot) usesGID(level iface.Level) bool {
+	for slot, user := range snap.gidUsers {
+		if user != nil && snap.slots[slot].Level <= level && user.UsesGoroutineID() {
+			return true
 		}
 	}
+	return false
 }
@@ -35,9 +35,11 @@ func NewAsync(writer iface.Writer, cap int) *Async {
 
 // Write implements the interface Writer. It sends the bs and record to the
 // internal channel. Another goroutine will receive them from the channel and
-// then calls the underlying Writer with them.
+// then calls the underlying Writer with them. The bs is copied since it may be
+// released after Write returns.
 // If the channel is full, it blocks.
 func (async *Async) Write(bs []byte, record *iface.Record) {
+	bs = append(make([]byte, 0, len(bs)), bs...)
 	async.chanData <- logData{Bytes: bs, Record: record}
 }
 
@@ -0,0 +1,139 @@
+package file
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+var levelNames = []string{
+	iface.Trace: "trace",
+	iface.Debug: "debug",
+	iface.Info:  "info",
+	iface.Warn:  "warn",
+	iface.Error: "error",
+	iface.Fatal: "fatal",
+}
+
+// A LevelWriter implements the interface iface.Writer. It holds one file Writer
+// per level and writes each log to the file Writer of its level. All the file
+// Writers share the same config except for the Base, which is suffixed with
+// <Separator><level name>, e.g. "app.error".
+//
+// All methods of a LevelWriter are concurrency safe.
+// A LevelWriter MUST be created with OpenLevels.
+type LevelWriter struct {
+	writers [iface.Off]*Writer
+	config  Config
+
+	lock sync.Mutex
+}
+
+// OpenLevels creates a new LevelWriter with the config. A file Writer will be
+// created for each of the levels. If no level is specified, all levels between
+// Trace and Fatal inclusive are used. Logs of the other levels are ignored.
+func OpenLevels(config Config, levels ...iface.Level) (*LevelWriter, error) {
+	if len(levels) == 0 {
+		levels = []iface.Level{iface.Trace, iface.Debug, iface.Info,
+			iface.Warn, iface.Error, iface.Fatal}
+	}
+	config.setDefaults()
+	if err := config.check(); err != nil {
+		return nil, fmt.Errorf("writer/file.OpenLevels: %v", err)
+	}
+	levelWriter := &LevelWriter{config: config}
+	for _, level := range levels {
+		if level < iface.Trace || level > iface.Fatal {
+			return nil, fmt.Errorf("writer/file.OpenLevels: invalid level %d", level)
+		}
+		levelWriter.writers[level] = &Writer{config: levelConfig(config, level)}
+	}
+	return levelWriter, nil
+}
+
+// Close closes all the file Writers of the LevelWriter. If any of them fails,
+// the others are still closed and the first error is returned.
+func (levelWriter *LevelWriter) Close() error {
+	var err error
+	for _, writer := range levelWriter.writers {
+		if writer == nil {
+			continue
+		}
+		if cerr := writer.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("writer/file.LevelWriter.Close: %v", err)
+	}
+	return nil
+}
+
+// Write implements the interface Writer. It writes logs to the file of their
+// level.
+func (levelWriter *LevelWriter) Write(bs []byte, record *iface.Record) {
+	if writer := levelWriter.Writer(record.Level); writer != nil {
+		writer.Write(bs, record)
+	}
+}
+
+// Writer returns the file Writer of the level. It returns nil if there is
+// no file Writer of the level.
+func (levelWriter *LevelWriter) Writer(level iface.Level) *Writer {
+	if level < iface.Trace || level > iface.Fatal {
+		return nil
+	}
+	return levelWriter.writers[level]
+}
+
+// Config returns the Config shared by all the file Writers of the LevelWriter.
+func (levelWriter *LevelWriter) Config() Config {
+	levelWriter.lock.Lock()
+	defer levelWriter.lock.Unlock()
+
+	return levelWriter.config
+}
+
+// UpdateConfig calls the fn with the Config shared by all the file Writers,
+// and then sets the returned config to each of them with its Base suffixed.
+// The fn must NOT be nil.
+// If the returned config is invalid, it returns an error and the Config of
+// the LevelWriter is left to be unchanged. Otherwise, the config is set to all
+// the file Writers before their files are closed, so an error closing a file
+// is returned with the new config already in effect.
+//
+// Do NOT call any method of the Writer or the Logger within the fn,
+// or it may deadlock.
+func (levelWriter *LevelWriter) UpdateConfig(fn func(Config) Config) error {
+	levelWriter.lock.Lock()
+	defer levelWriter.lock.Unlock()
+
+	config := fn(levelWriter.config)
+	config.setDefaults()
+	if err := config.check(); err != nil {
+		return fmt.Errorf("writer/file.LevelWriter.UpdateConfig: %v", err)
+	}
+	var err error
+	for level, writer := range levelWriter.writers {
+		if writer == nil {
+			continue
+		}
+		detached := writer.applyConfig(levelConfig(config, iface.Level(level)))
+		if detached != nil {
+			if cerr := detached.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	}
+	levelWriter.config = config
+	if err != nil {
+		return fmt.Errorf("writer/file.LevelWriter.UpdateConfig: %v", err)
+	}
+	return nil
+}
+
+func levelConfig(config Config, level iface.Level) Config {
+	config.Base += config.Separator + levelNames[level]
+	return config
+}
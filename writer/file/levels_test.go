@@ -0,0 +1,123 @@
+package file_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/file"
+)
+
+func TestLevelWriter(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	levelWriter, err := file.OpenLevels(file.Config{
+		Path:         dir,
+		Base:         "app",
+		NoDirForDays: true,
+	}, iface.Info, iface.Error)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(levelWriter, iface.Info, "info 1\n")
+	write(levelWriter, iface.Error, "error 1\n")
+	write(levelWriter, iface.Info, "info 2\n")
+	write(levelWriter, iface.Debug, "debug 1\n")
+	if err := levelWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkFile(t, dir, "app.info.*.log", "info 1\ninfo 2\n")
+	checkFile(t, dir, "app.error.*.log", "error 1\n")
+	checkFile(t, dir, "app.debug.*.log", "")
+	if levelWriter.Writer(iface.Debug) != nil {
+		t.Error("TestLevelWriter: unexpected Writer of level Debug")
+	}
+}
+
+func TestLevelWriterUpdateConfig(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	levelWriter, err := file.OpenLevels(file.Config{
+		Path:         dir,
+		Base:         "app",
+		NoDirForDays: true,
+	}, iface.Warn, iface.Error)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer levelWriter.Close()
+	write(levelWriter, iface.Warn, "warn 1\n")
+
+	err = levelWriter.UpdateConfig(func(config file.Config) file.Config {
+		config.MaxFileSize = -1
+		return config
+	})
+	if err == nil {
+		t.Fatal("TestLevelWriterUpdateConfig: expect an error")
+	}
+	if base := levelWriter.Writer(iface.Warn).Config().Base; base != "app.warn" {
+		t.Errorf("TestLevelWriterUpdateConfig: unexpected base %q", base)
+	}
+
+	err = levelWriter.UpdateConfig(func(config file.Config) file.Config {
+		config.Base = "new"
+		return config
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, level := range []iface.Level{iface.Warn, iface.Error} {
+		if config := levelWriter.Writer(level).Config(); !strings.HasPrefix(config.Base, "new.") {
+			t.Errorf("TestLevelWriterUpdateConfig: unexpected base %q", config.Base)
+		}
+	}
+	write(levelWriter, iface.Warn, "warn 2\n")
+	if err := levelWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkFile(t, dir, "app.warn.*.log", "warn 1\n")
+	checkFile(t, dir, "new.warn.*.log", "warn 2\n")
+}
+
+func write(writer iface.Writer, level iface.Level, log string) {
+	writer.Write([]byte(log), &iface.Record{Time: time.Now(), Level: level})
+}
+
+func checkFile(t *testing.T, dir, pattern, expect string) {
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect == "" {
+		if len(matches) != 0 {
+			t.Errorf("checkFile: unexpected files %q", matches)
+		}
+		return
+	}
+	if len(matches) != 1 {
+		t.Fatalf("checkFile: expect 1 file of %q, got %q", pattern, matches)
+	}
+	data, err := ioutil.ReadFile(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expect {
+		t.Errorf("checkFile:\noutput: %q\nexpect: %q", data, expect)
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gxlog-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
@@ -236,6 +236,22 @@ func (writer *Writer) needNewFile(config *Config) bool {
 	return false
 }
 
+// applyConfig sets the config, which MUST have been checked, to the Writer.
+// If the config needs a new file, the current file is detached from the Writer
+// and returned to be closed by the caller.
+func (writer *Writer) applyConfig(config Config) io.WriteCloser {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	var detached io.WriteCloser
+	if writer.needNewFile(&config) {
+		detached = writer.writer
+		writer.writer = nil
+	}
+	writer.config = config
+	return detached
+}
+
 func (writer *Writer) setConfig(config *Config) error {
 	config.setDefaults()
 	if err := config.check(); err != nil {
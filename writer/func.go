@@ -6,6 +6,8 @@ import (
 )
 
 // The Func type is a function wrapper to the interface Writer.
+// The function is called by multiple goroutines concurrently, so it MUST be
+// concurrency safe.
 // Do NOT call any method of the Logger within the function, or it may deadlock.
 type Func func(bs []byte, record *iface.Record)
 
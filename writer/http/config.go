@@ -0,0 +1,141 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/fufuok/gxlog/writer"
+)
+
+// The Protocol defines the type of protocol of ingestion endpoints.
+type Protocol int
+
+// All available protocols here.
+const (
+	// Logs are sent as lines, each of which is a log without trailing line
+	// feeds, e.g. the output of a json formatter as JSON lines.
+	JSONLines Protocol = iota
+	// Logs are sent with the push API of Loki. Each log is a line of the stream
+	// labeled with LokiLabels and its level, e.g. {level="warn"}.
+	Loki
+	// Logs are sent with the _bulk API of Elasticsearch. Each log is indexed
+	// into ESIndex as a document, so it MUST be a JSON object, e.g. the output
+	// of a json formatter.
+	Elasticsearch
+)
+
+// A Config is used to configure an http batch writer.
+type Config struct {
+	// URL is the URL of the ingestion endpoint, e.g.
+	// "http://loki:3100/loki/api/v1/push" or "http://es:9200/_bulk".
+	// It MUST be specified.
+	URL string
+	// Protocol is the protocol of the ingestion endpoint.
+	// If Protocol is not specified, JSONLines is used.
+	Protocol Protocol
+	// Header is added to each request if it is not nil.
+	Header http.Header
+	// Username and Password are used for the basic authentication if Username
+	// is not empty.
+	Username string
+	Password string
+	// BearerToken is used for the bearer authentication if it is not empty.
+	BearerToken string
+	// Gzip specifies whether to compress request bodies with gzip.
+	Gzip bool
+	// LokiLabels is the labels of streams with the Loki protocol. The label
+	// "level" is added with the level name of logs.
+	LokiLabels map[string]string
+	// ESIndex is the index of documents with the Elasticsearch protocol.
+	// If ESIndex is not specified, "gxlog" is used.
+	ESIndex string
+	// BatchSize is the max count of logs in a batch.
+	// If BatchSize is not specified, 1000 is used.
+	BatchSize int
+	// BatchBytes is the max size in bytes of logs in a batch. A batch is sent
+	// once it reaches either BatchSize or BatchBytes.
+	// If BatchBytes is not specified, 1 MB is used.
+	BatchBytes int
+	// FlushInterval is the interval to send the pending batch even if it is
+	// not full.
+	// If FlushInterval is not specified, (1 * time.Second) is used.
+	FlushInterval time.Duration
+	// QueueSize is the max count of batches waiting to be sent. Batches are
+	// dropped while the queue is full, and each log of them is reported to
+	// the ErrorHandler.
+	// If QueueSize is not specified, 16 is used.
+	QueueSize int
+	// Timeout is the timeout of each request.
+	// If Timeout is not specified, (10 * time.Second) is used.
+	Timeout time.Duration
+	// MaxRetries is the max count of retries of a batch after a network error,
+	// a 5xx or 429 response. If it is negative, batches are never retried.
+	// If MaxRetries is not specified, 3 is used.
+	MaxRetries int
+	// MinBackoff is the interval before the first retry of a batch. It doubles
+	// after each retry until it reaches MaxBackoff. The Retry-After header of a
+	// response takes precedence over it.
+	// If MinBackoff is not specified, (500 * time.Millisecond) is used.
+	MinBackoff time.Duration
+	// MaxBackoff is the max interval between retries. It also caps the interval
+	// in the Retry-After header of a response.
+	// If MaxBackoff is not specified, (30 * time.Second) is used.
+	MaxBackoff time.Duration
+	// Client is used to send requests if it is not nil. Otherwise, a client
+	// with Timeout is used.
+	Client *http.Client
+	// ErrorHandler will be called with each log of a batch that fails to be
+	// delivered if it is not nil.
+	ErrorHandler writer.ErrorHandler
+}
+
+func (config *Config) setDefaults() {
+	if config.ESIndex == "" {
+		config.ESIndex = "gxlog"
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 1000
+	}
+	if config.BatchBytes == 0 {
+		config.BatchBytes = 1 << 20
+	}
+	if config.FlushInterval == 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.QueueSize == 0 {
+		config.QueueSize = 16
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+	if config.MinBackoff == 0 {
+		config.MinBackoff = 500 * time.Millisecond
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+}
+
+func (config *Config) check() error {
+	if config.URL == "" {
+		return errors.New("Config.URL must be specified")
+	}
+	if config.Protocol < JSONLines || config.Protocol > Elasticsearch {
+		return errors.New("Config.Protocol is invalid")
+	}
+	if config.BatchSize < 0 || config.BatchBytes < 0 || config.QueueSize < 0 {
+		return errors.New("Config.BatchSize, Config.BatchBytes and Config.QueueSize " +
+			"must NOT be negative")
+	}
+	if config.FlushInterval < 0 || config.Timeout < 0 {
+		return errors.New("Config.FlushInterval and Config.Timeout must NOT be negative")
+	}
+	if config.MinBackoff < 0 || config.MaxBackoff < config.MinBackoff {
+		return errors.New("Config.MinBackoff or Config.MaxBackoff is invalid")
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+var levelNames = []string{
+	iface.Trace: "trace",
+	iface.Debug: "debug",
+	iface.Info:  "info",
+	iface.Warn:  "warn",
+	iface.Error: "error",
+	iface.Fatal: "fatal",
+}
+
+var contentTypes = []string{
+	JSONLines:     "application/x-ndjson",
+	Loki:          "application/json",
+	Elasticsearch: "application/x-ndjson",
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiPush struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+func appendJSONLines(buf []byte, entries []entry) []byte {
+	for i := range entries {
+		buf = append(buf, trimNewline(entries[i].Bytes)...)
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+// appendLoki appends a push request of Loki with a stream per level.
+func appendLoki(buf []byte, entries []entry, labels map[string]string) ([]byte, error) {
+	var push lokiPush
+	streams := make(map[iface.Level]*lokiStream)
+	for i := range entries {
+		record := &entries[i].Record
+		stream := streams[record.Level]
+		if stream == nil {
+			stream = &lokiStream{Stream: make(map[string]string, len(labels)+1)}
+			for key, value := range labels {
+				stream.Stream[key] = value
+			}
+			stream.Stream["level"] = levelName(record.Level)
+			streams[record.Level] = stream
+			push.Streams = append(push.Streams, stream)
+		}
+		ts := strconv.FormatInt(record.Time.UnixNano(), 10)
+		line := string(trimNewline(entries[i].Bytes))
+		stream.Values = append(stream.Values, [2]string{ts, line})
+	}
+	bs, err := json.Marshal(&push)
+	if err != nil {
+		return buf, err
+	}
+	return append(buf, bs...), nil
+}
+
+func appendBulk(buf []byte, entries []entry, index string) []byte {
+	action, _ := json.Marshal(map[string]map[string]string{
+		"index": {"_index": index},
+	})
+	for i := range entries {
+		buf = append(buf, action...)
+		buf = append(buf, '\n')
+		buf = append(buf, trimNewline(entries[i].Bytes)...)
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+func levelName(level iface.Level) string {
+	if level < iface.Trace || level > iface.Fatal {
+		return "unknown"
+	}
+	return levelNames[level]
+}
+
+func trimNewline(bs []byte) []byte {
+	for len(bs) > 0 && (bs[len(bs)-1] == '\n' || bs[len(bs)-1] == '\r') {
+		bs = bs[:len(bs)-1]
+	}
+	return bs
+}
@@ -0,0 +1,383 @@
+// Package http implements an http batch writer which implements the Writer.
+// It ships logs to an http ingestion endpoint without a sidecar, e.g.
+// a collector accepting JSON lines, Loki or Elasticsearch.
+//
+// Logs are batched and sent by another goroutine. A batch is sent once it is
+// full or it has been pending for the flush interval. A batch that fails with
+// a network error, a 5xx or 429 response is retried with exponential backoff,
+// honouring the Retry-After header of the response.
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+var (
+	errClosed    = errors.New("writer/http: the writer is closed")
+	errQueueFull = errors.New("writer/http: the queue is full")
+)
+
+// Stats is the statistics of an http batch writer.
+type Stats struct {
+	// SentBatches is the total count of batches delivered.
+	SentBatches int64
+	// SentLogs is the total count of logs delivered.
+	SentLogs int64
+	// SentBytes is the total size of logs delivered, before compression.
+	SentBytes int64
+	// FailedLogs is the total count of logs in batches that failed to be
+	// delivered after retries.
+	FailedLogs int64
+	// DroppedLogs is the total count of logs in batches dropped while the
+	// queue is full or written after Close.
+	DroppedLogs int64
+	// Retries is the total count of retries of batches.
+	Retries int64
+}
+
+type entry struct {
+	Bytes  []byte
+	Record iface.Record
+}
+
+type batch struct {
+	entries []entry
+	size    int
+}
+
+// A Writer implements the interface iface.Writer.
+//
+// All methods of a Writer are concurrency safe.
+// A Writer MUST be created with Open.
+type Writer struct {
+	config Config
+	client *http.Client
+
+	batch     *batch
+	queue     chan *batch
+	pending   int // count of batches in the queue or being sent
+	cond      *sync.Cond
+	stats     Stats
+	closed    bool
+	chanClose chan struct{}
+	wg        sync.WaitGroup
+
+	lock sync.Mutex
+}
+
+// Open creates a new Writer with the config.
+func Open(config Config) (*Writer, error) {
+	config.setDefaults()
+	if err := config.check(); err != nil {
+		return nil, fmt.Errorf("writer/http.Open: %v", err)
+	}
+	client := config.Client
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+	wt := &Writer{
+		config:    config,
+		client:    client,
+		queue:     make(chan *batch, config.QueueSize),
+		chanClose: make(chan struct{}),
+	}
+	wt.cond = sync.NewCond(&wt.lock)
+	wt.wg.Add(2)
+	go wt.flushPeriodically()
+	go wt.deliverAll()
+	return wt, nil
+}
+
+// Close sends the pending batch, and then waits until all the batches are
+// delivered or failed. Batches are NOT retried any more after Close, a batch
+// failing to be delivered fails with its last error. Logs written after Close
+// are dropped.
+func (writer *Writer) Close() error {
+	writer.lock.Lock()
+	if writer.closed {
+		writer.lock.Unlock()
+		return nil
+	}
+	writer.closed = true
+	b := writer.batch
+	writer.batch = nil
+	if b != nil {
+		writer.pending++
+	}
+	writer.lock.Unlock()
+
+	close(writer.chanClose)
+	if b != nil {
+		writer.queue <- b
+	}
+	close(writer.queue)
+	writer.wg.Wait()
+	return nil
+}
+
+// Write implements the interface Writer. It appends the bs and record to the
+// pending batch.
+func (writer *Writer) Write(bs []byte, record *iface.Record) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.closed {
+		writer.stats.DroppedLogs++
+		writer.handleError(bs, record, errClosed)
+		return
+	}
+	if writer.batch == nil {
+		writer.batch = &batch{}
+	}
+	b := writer.batch
+	b.entries = append(b.entries, entry{
+		Bytes:  append([]byte(nil), bs...),
+		Record: *record,
+	})
+	b.size += len(bs)
+	if len(b.entries) >= writer.config.BatchSize || b.size >= writer.config.BatchBytes {
+		writer.enqueue()
+	}
+}
+
+// Flush sends the pending batch, and then waits until all the batches are
+// delivered or failed.
+func (writer *Writer) Flush() {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if !writer.closed {
+		writer.enqueue()
+	}
+	for writer.pending > 0 {
+		writer.cond.Wait()
+	}
+}
+
+// Stats returns the delivery statistics of the Writer.
+func (writer *Writer) Stats() Stats {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.stats
+}
+
+// enqueue MUST be called with the lock held.
+func (writer *Writer) enqueue() {
+	b := writer.batch
+	if b == nil {
+		return
+	}
+	writer.batch = nil
+	select {
+	case writer.queue <- b:
+		writer.pending++
+	default:
+		writer.stats.DroppedLogs += int64(len(b.entries))
+		writer.handleBatchError(b, errQueueFull)
+	}
+}
+
+func (writer *Writer) flushPeriodically() {
+	defer writer.wg.Done()
+
+	ticker := time.NewTicker(writer.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			writer.lock.Lock()
+			if !writer.closed {
+				writer.enqueue()
+			}
+			writer.lock.Unlock()
+		case <-writer.chanClose:
+			return
+		}
+	}
+}
+
+func (writer *Writer) deliverAll() {
+	defer writer.wg.Done()
+
+	for b := range writer.queue {
+		retries, err := writer.deliver(b)
+
+		writer.lock.Lock()
+		writer.stats.Retries += int64(retries)
+		if err == nil {
+			writer.stats.SentBatches++
+			writer.stats.SentLogs += int64(len(b.entries))
+			writer.stats.SentBytes += int64(b.size)
+		} else {
+			writer.stats.FailedLogs += int64(len(b.entries))
+			writer.handleBatchError(b, err)
+		}
+		writer.pending--
+		writer.cond.Broadcast()
+		writer.lock.Unlock()
+	}
+}
+
+func (writer *Writer) deliver(b *batch) (retries int, err error) {
+	body, err := writer.encode(b)
+	if err != nil {
+		return 0, err
+	}
+	backoff := writer.config.MinBackoff
+	for {
+		retryable, retryAfter, err := writer.post(body)
+		if err == nil || !retryable || retries >= writer.config.MaxRetries {
+			return retries, err
+		}
+		if retryAfter <= 0 {
+			retryAfter = backoff
+			backoff *= 2
+			if backoff > writer.config.MaxBackoff {
+				backoff = writer.config.MaxBackoff
+			}
+		} else if retryAfter > writer.config.MaxBackoff {
+			retryAfter = writer.config.MaxBackoff
+		}
+		if !writer.wait(retryAfter) {
+			return retries, err
+		}
+		retries++
+	}
+}
+
+// wait waits for the duration. It returns false if the Writer is closed before
+// the duration elapses.
+func (writer *Writer) wait(duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-writer.chanClose:
+		return false
+	}
+}
+
+func (writer *Writer) encode(b *batch) ([]byte, error) {
+	buf := make([]byte, 0, b.size+len(b.entries)*2)
+	var err error
+	switch writer.config.Protocol {
+	case Loki:
+		buf, err = appendLoki(buf, b.entries, writer.config.LokiLabels)
+	case Elasticsearch:
+		buf = appendBulk(buf, b.entries, writer.config.ESIndex)
+	default:
+		buf = appendJSONLines(buf, b.entries)
+	}
+	if err != nil || !writer.config.Gzip {
+		return buf, err
+	}
+	var gzBuf bytes.Buffer
+	gz := gzip.NewWriter(&gzBuf)
+	if _, err := gz.Write(buf); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return gzBuf.Bytes(), nil
+}
+
+// post sends the body to the endpoint. It returns whether the request is
+// retryable and the interval in the Retry-After header if there is one.
+func (writer *Writer) post(body []byte) (bool, time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, writer.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, err
+	}
+	for key, values := range writer.config.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", contentTypes[writer.config.Protocol])
+	if writer.config.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if writer.config.Username != "" {
+		req.SetBasicAuth(writer.config.Username, writer.config.Password)
+	}
+	if writer.config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+writer.config.BearerToken)
+	}
+	resp, err := writer.client.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return true, 0, err
+	}
+	status := resp.StatusCode
+	if status >= 200 && status < 300 {
+		if writer.config.Protocol == Elasticsearch {
+			return false, 0, checkBulkResponse(respBody)
+		}
+		return false, 0, nil
+	}
+	err = fmt.Errorf("writer/http: unexpected status %q: %s", resp.Status,
+		bytes.TrimSpace(respBody))
+	if status == http.StatusTooManyRequests || status >= 500 {
+		return true, parseRetryAfter(resp.Header.Get("Retry-After")), err
+	}
+	return false, 0, err
+}
+
+func (writer *Writer) handleBatchError(b *batch, err error) {
+	for i := range b.entries {
+		writer.handleError(b.entries[i].Bytes, &b.entries[i].Record, err)
+	}
+}
+
+func (writer *Writer) handleError(bs []byte, record *iface.Record, err error) {
+	if writer.config.ErrorHandler != nil {
+		writer.config.ErrorHandler(bs, record, err)
+	}
+}
+
+// checkBulkResponse checks the response of the _bulk API of Elasticsearch.
+// Failed items are NOT retried to avoid duplicate documents.
+func checkBulkResponse(body []byte) error {
+	var resp struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("writer/http: invalid bulk response: %v", err)
+	}
+	if resp.Errors {
+		return errors.New("writer/http: some documents failed to be indexed")
+	}
+	return nil
+}
+
+// parseRetryAfter parses the Retry-After header in either delay seconds or
+// an http date. It returns 0 if the header is absent or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
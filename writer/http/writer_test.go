@@ -0,0 +1,285 @@
+package http_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	gxhttp "github.com/fufuok/gxlog/writer/http"
+)
+
+var tmplRecord = iface.Record{
+	Time:  time.Unix(1533107527, 235605270),
+	Level: iface.Warn,
+	Msg:   "testing",
+}
+
+type collector struct {
+	requests   []*http.Request
+	bodies     []string
+	statuses   []int
+	retryAfter string
+
+	lock sync.Mutex
+}
+
+func (clt *collector) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	clt.lock.Lock()
+	defer clt.lock.Unlock()
+
+	var body []byte
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(req.Body)
+		if err == nil {
+			body, _ = ioutil.ReadAll(gz)
+		}
+	} else {
+		body, _ = ioutil.ReadAll(req.Body)
+	}
+	clt.requests = append(clt.requests, req)
+	clt.bodies = append(clt.bodies, string(body))
+	status := http.StatusOK
+	if len(clt.statuses) > 0 {
+		status = clt.statuses[0]
+		clt.statuses = clt.statuses[1:]
+	}
+	if status == http.StatusTooManyRequests {
+		retryAfter := clt.retryAfter
+		if retryAfter == "" {
+			retryAfter = "1"
+		}
+		rw.Header().Set("Retry-After", retryAfter)
+	}
+	rw.WriteHeader(status)
+	if strings.HasSuffix(req.URL.Path, "/_bulk") {
+		rw.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+	}
+}
+
+func (clt *collector) Bodies() []string {
+	clt.lock.Lock()
+	defer clt.lock.Unlock()
+
+	return clt.bodies
+}
+
+func TestJSONLines(t *testing.T) {
+	clt := &collector{}
+	server := httptest.NewServer(clt)
+	defer server.Close()
+
+	wt, err := gxhttp.Open(gxhttp.Config{
+		URL:         server.URL,
+		Header:      http.Header{"X-Scope-Orgid": {"team"}},
+		BearerToken: "token",
+		Gzip:        true,
+		BatchSize:   2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+
+	for _, msg := range []string{`{"msg":"a"}` + "\n", `{"msg":"b"}`, `{"msg":"c"}`} {
+		wt.Write([]byte(msg), &tmplRecord)
+	}
+	wt.Flush()
+
+	expect := []string{`{"msg":"a"}` + "\n" + `{"msg":"b"}` + "\n", `{"msg":"c"}` + "\n"}
+	checkBodies(t, clt.Bodies(), expect)
+	req := clt.requests[0]
+	if req.Header.Get("X-Scope-Orgid") != "team" ||
+		req.Header.Get("Authorization") != "Bearer token" ||
+		req.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("TestJSONLines: unexpected header: %v", req.Header)
+	}
+	stats := wt.Stats()
+	if stats.SentBatches != 2 || stats.SentLogs != 3 {
+		t.Errorf("TestJSONLines: unexpected stats: %+v", stats)
+	}
+}
+
+func TestLoki(t *testing.T) {
+	clt := &collector{}
+	server := httptest.NewServer(clt)
+	defer server.Close()
+
+	wt, err := gxhttp.Open(gxhttp.Config{
+		URL:        server.URL + "/loki/api/v1/push",
+		Protocol:   gxhttp.Loki,
+		LokiLabels: map[string]string{"app": "test"},
+		Username:   "user",
+		Password:   "pass",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+
+	record := tmplRecord
+	wt.Write([]byte("a\n"), &record)
+	record.Level = iface.Error
+	wt.Write([]byte("b\n"), &record)
+	wt.Flush()
+
+	expect := `{"streams":[` +
+		`{"stream":{"app":"test","level":"warn"},"values":[["1533107527235605270","a"]]},` +
+		`{"stream":{"app":"test","level":"error"},"values":[["1533107527235605270","b"]]}]}`
+	checkBodies(t, clt.Bodies(), []string{expect})
+	if user, pass, ok := clt.requests[0].BasicAuth(); !ok || user != "user" || pass != "pass" {
+		t.Errorf("TestLoki: unexpected basic auth: %q %q", user, pass)
+	}
+}
+
+func TestElasticsearch(t *testing.T) {
+	clt := &collector{}
+	server := httptest.NewServer(clt)
+	defer server.Close()
+
+	wt, err := gxhttp.Open(gxhttp.Config{
+		URL:      server.URL + "/_bulk",
+		Protocol: gxhttp.Elasticsearch,
+		ESIndex:  "logs",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+
+	wt.Write([]byte(`{"msg":"a"}`+"\n"), &tmplRecord)
+	wt.Flush()
+
+	expect := `{"index":{"_index":"logs"}}` + "\n" + `{"msg":"a"}` + "\n"
+	checkBodies(t, clt.Bodies(), []string{expect})
+}
+
+func TestRetry(t *testing.T) {
+	clt := &collector{
+		statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests,
+			http.StatusOK, http.StatusBadRequest},
+	}
+	server := httptest.NewServer(clt)
+	defer server.Close()
+
+	var failed []string
+	wt, err := gxhttp.Open(gxhttp.Config{
+		URL:        server.URL,
+		MinBackoff: 10 * time.Millisecond,
+		ErrorHandler: func(bs []byte, _ *iface.Record, _ error) {
+			failed = append(failed, string(bs))
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+
+	begin := time.Now()
+	wt.Write([]byte("a"), &tmplRecord)
+	wt.Flush()
+	if elapsed := time.Since(begin); elapsed < time.Second {
+		t.Errorf("TestRetry: Retry-After is NOT honoured, elapsed: %v", elapsed)
+	}
+	wt.Write([]byte("b"), &tmplRecord)
+	wt.Flush()
+
+	checkBodies(t, clt.Bodies(), []string{"a\n", "a\n", "a\n", "b\n"})
+	if len(failed) != 1 || failed[0] != "b" {
+		t.Errorf("TestRetry: unexpected failed logs: %q", failed)
+	}
+	stats := wt.Stats()
+	if stats.Retries != 2 || stats.SentLogs != 1 || stats.FailedLogs != 1 {
+		t.Errorf("TestRetry: unexpected stats: %+v", stats)
+	}
+}
+
+func TestRetryAfterClamped(t *testing.T) {
+	clt := &collector{
+		statuses:   []int{http.StatusTooManyRequests, http.StatusOK},
+		retryAfter: "3600",
+	}
+	server := httptest.NewServer(clt)
+	defer server.Close()
+
+	wt, err := gxhttp.Open(gxhttp.Config{
+		URL:        server.URL,
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+
+	begin := time.Now()
+	wt.Write([]byte("a"), &tmplRecord)
+	wt.Flush()
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Errorf("TestRetryAfterClamped: Retry-After is NOT clamped, elapsed: %v", elapsed)
+	}
+	checkBodies(t, clt.Bodies(), []string{"a\n", "a\n"})
+}
+
+func TestCloseAbortsRetry(t *testing.T) {
+	clt := &collector{
+		statuses:   []int{http.StatusTooManyRequests},
+		retryAfter: "3600",
+	}
+	server := httptest.NewServer(clt)
+	defer server.Close()
+
+	var failed []string
+	var lock sync.Mutex
+	wt, err := gxhttp.Open(gxhttp.Config{
+		URL:        server.URL,
+		MaxBackoff: time.Hour,
+		ErrorHandler: func(bs []byte, _ *iface.Record, _ error) {
+			lock.Lock()
+			failed = append(failed, string(bs))
+			lock.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wt.Write([]byte("a"), &tmplRecord)
+	done := make(chan struct{})
+	go func() {
+		wt.Flush()
+		close(done)
+	}()
+	for len(clt.Bodies()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	begin := time.Now()
+	if err := wt.Close(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Errorf("TestCloseAbortsRetry: Close is blocked, elapsed: %v", elapsed)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(failed) != 1 || failed[0] != "a" {
+		t.Errorf("TestCloseAbortsRetry: unexpected failed logs: %q", failed)
+	}
+}
+
+func checkBodies(t *testing.T, bodies, expect []string) {
+	if len(bodies) != len(expect) {
+		t.Fatalf("checkBodies:\noutput: %q\nexpect: %q", bodies, expect)
+	}
+	for i := range expect {
+		if bodies[i] != expect[i] {
+			t.Errorf("checkBodies:\noutput: %q\nexpect: %q", bodies[i], expect[i])
+		}
+	}
+}
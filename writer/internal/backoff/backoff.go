@@ -0,0 +1,46 @@
+// Package backoff implements the exponential backoff shared by writers that
+// reconnect to remote backends.
+package backoff
+
+import (
+	"time"
+)
+
+// A Backoff decides whether it is time to retry after failures.
+// It is NOT concurrency safe.
+type Backoff struct {
+	min     time.Duration
+	max     time.Duration
+	current time.Duration
+	next    time.Time
+}
+
+// New creates a new Backoff. The interval starts with min and doubles after
+// each failure until it reaches max.
+func New(min, max time.Duration) *Backoff {
+	return &Backoff{min: min, max: max}
+}
+
+// Ready returns whether it is time to retry.
+func (backoff *Backoff) Ready(now time.Time) bool {
+	return !now.Before(backoff.next)
+}
+
+// Fail records a failure at now and schedules the next retry.
+func (backoff *Backoff) Fail(now time.Time) {
+	if backoff.current == 0 {
+		backoff.current = backoff.min
+	} else {
+		backoff.current *= 2
+	}
+	if backoff.current > backoff.max {
+		backoff.current = backoff.max
+	}
+	backoff.next = now.Add(backoff.current)
+}
+
+// Reset forgets all failures.
+func (backoff *Backoff) Reset() {
+	backoff.current = 0
+	backoff.next = time.Time{}
+}
@@ -0,0 +1,212 @@
+// Package filter implements the filter syntax shared by writers that let their
+// subscribers choose which logs to receive.
+//
+// A filter consists of terms separated by spaces and a log matches the filter
+// only if it matches all the terms, e.g.
+//
+//	level>=warn pkg=github.com/foo/db ctx.reqid=abc
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+// A Filter reports whether a log matches it.
+type Filter func(record *iface.Record) bool
+
+var levelNames = map[string]iface.Level{
+	"trace": iface.Trace,
+	"debug": iface.Debug,
+	"info":  iface.Info,
+	"warn":  iface.Warn,
+	"error": iface.Error,
+	"fatal": iface.Fatal,
+	"t":     iface.Trace,
+	"d":     iface.Debug,
+	"i":     iface.Info,
+	"w":     iface.Warn,
+	"e":     iface.Error,
+	"f":     iface.Fatal,
+}
+
+var operators = []string{">=", "<=", "!=", "=", ">", "<", "~"}
+
+// Parse parses a filter. It returns nil if the line has no term.
+func Parse(line string) (Filter, error) {
+	var filters []Filter
+	for _, term := range strings.Fields(line) {
+		filter, err := parseTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return func(record *iface.Record) bool {
+		for _, filter := range filters {
+			if !filter(record) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func parseTerm(term string) (Filter, error) {
+	key, op, value := splitTerm(term)
+	// names of builtin fields are case-insensitive while context keys are not
+	name := strings.ToLower(key)
+	switch {
+	case name == "level":
+		return parseLevelTerm(op, value)
+	case name == "marked":
+		return parseMarkedTerm(op, value)
+	case name == "file":
+		return parseStrTerm(op, value, func(record *iface.Record) string {
+			return record.File
+		})
+	case name == "pkg":
+		return parseStrTerm(op, value, func(record *iface.Record) string {
+			return record.Pkg
+		})
+	case name == "func":
+		return parseStrTerm(op, value, func(record *iface.Record) string {
+			return record.Func
+		})
+	case name == "prefix":
+		return parseStrTerm(op, value, func(record *iface.Record) string {
+			return record.Aux.Prefix
+		})
+	case name == "msg":
+		return parseStrTerm(op, value, func(record *iface.Record) string {
+			return record.Msg
+		})
+	case strings.HasPrefix(name, "ctx.") && len(key) > len("ctx."):
+		return parseContextTerm(key[len("ctx."):], op, value)
+	}
+	return nil, fmt.Errorf("unknown term %q", term)
+}
+
+func splitTerm(term string) (key, op, value string) {
+	index := strings.IndexAny(term, "<>!=~")
+	if index < 0 {
+		return term, "", ""
+	}
+	key = term[:index]
+	for _, op := range operators {
+		if strings.HasPrefix(term[index:], op) {
+			return key, op, term[index+len(op):]
+		}
+	}
+	return key, term[index : index+1], term[index+1:]
+}
+
+func parseLevelTerm(op, value string) (Filter, error) {
+	level, ok := levelNames[strings.ToLower(value)]
+	if !ok {
+		return nil, fmt.Errorf("unknown level %q", value)
+	}
+	var cmp func(iface.Level) bool
+	switch op {
+	case ">=":
+		cmp = func(lv iface.Level) bool { return lv >= level }
+	case "<=":
+		cmp = func(lv iface.Level) bool { return lv <= level }
+	case ">":
+		cmp = func(lv iface.Level) bool { return lv > level }
+	case "<":
+		cmp = func(lv iface.Level) bool { return lv < level }
+	case "=":
+		cmp = func(lv iface.Level) bool { return lv == level }
+	case "!=":
+		cmp = func(lv iface.Level) bool { return lv != level }
+	default:
+		return nil, fmt.Errorf("invalid operator %q for level", op)
+	}
+	return func(record *iface.Record) bool {
+		return cmp(record.Level)
+	}, nil
+}
+
+func parseMarkedTerm(op, value string) (Filter, error) {
+	marked := true
+	if op != "" {
+		var err error
+		if marked, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("invalid value %q for marked", value)
+		}
+		switch op {
+		case "=":
+		case "!=":
+			marked = !marked
+		default:
+			return nil, fmt.Errorf("invalid operator %q for marked", op)
+		}
+	}
+	return func(record *iface.Record) bool {
+		return record.Aux.Marked == marked
+	}, nil
+}
+
+func parseStrTerm(op, value string, field func(*iface.Record) string) (Filter, error) {
+	var match func(string) bool
+	switch op {
+	case "=", "!=":
+		match = matchString(value)
+	case "~":
+		match = func(str string) bool { return strings.Contains(str, value) }
+	default:
+		return nil, fmt.Errorf("invalid operator %q for string", op)
+	}
+	negative := op == "!="
+	return func(record *iface.Record) bool {
+		return match(field(record)) != negative
+	}, nil
+}
+
+func parseContextTerm(key, op, value string) (Filter, error) {
+	if op == "" {
+		return func(record *iface.Record) bool {
+			for _, context := range record.Aux.Contexts {
+				if context.Key == key {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+	var match func(string) bool
+	switch op {
+	case "=", "!=":
+		match = matchString(value)
+	case "~":
+		match = func(str string) bool { return strings.Contains(str, value) }
+	default:
+		return nil, fmt.Errorf("invalid operator %q for context", op)
+	}
+	negative := op == "!="
+	return func(record *iface.Record) bool {
+		for _, context := range record.Aux.Contexts {
+			if context.Key == key && match(context.Value) {
+				return !negative
+			}
+		}
+		return negative
+	}, nil
+}
+
+// matchString returns a function matching the pattern exactly, or matching
+// the prefix of the pattern if the pattern ends with '*'.
+func matchString(pattern string) func(string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		prefix := pattern[:len(pattern)-1]
+		return func(str string) bool { return strings.HasPrefix(str, prefix) }
+	}
+	return func(str string) bool { return str == pattern }
+}
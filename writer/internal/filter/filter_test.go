@@ -0,0 +1,42 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/internal/filter"
+)
+
+var tmplRecord = iface.Record{
+	Level: iface.Warn,
+	Pkg:   "github.com/foo/db",
+	Aux: iface.Auxiliary{
+		Contexts: []iface.Context{
+			{Key: "requestID", Value: "abc"},
+		},
+	},
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		Line   string
+		Expect bool
+	}{
+		{"level>=warn", true},
+		{"LEVEL>=Error", false},
+		{"Pkg=github.com/foo/db ctx.requestID=abc", true},
+		{"CTX.requestID=abc", true},
+		{"ctx.requestID=xyz", false},
+		{"ctx.requestid=abc", false},
+		{"ctx.RequestID", false},
+	}
+	for _, tc := range testCases {
+		fn, err := filter.Parse(tc.Line)
+		if err != nil {
+			t.Fatalf("TestParse: %q: %v", tc.Line, err)
+		}
+		if output := fn(&tmplRecord); output != tc.Expect {
+			t.Errorf("TestParse: %q:\noutput: %v\nexpect: %v", tc.Line, output, tc.Expect)
+		}
+	}
+}
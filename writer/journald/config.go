@@ -0,0 +1,68 @@
+package journald
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer"
+)
+
+// The Priority defines the priority type of journal entries. It has the same
+// values with the severity of syslog.
+type Priority int
+
+// Priority definitions here to be cross compilation friendly.
+const (
+	PriEmerg Priority = iota
+	PriAlert
+	PriCrit
+	PriErr
+	PriWarning
+	PriNotice
+	PriInfo
+	PriDebug
+)
+
+// A Config is used to configure a journald writer.
+type Config struct {
+	// Addr is the pathname of the native protocol socket of journald.
+	// If Addr is not specified, "/run/systemd/journal/socket" is used.
+	Addr string
+	// Identifier is the SYSLOG_IDENTIFIER field of journal entries.
+	// If Identifier is not specified, filepath.Base(os.Args[0]) is used.
+	Identifier string
+	// PriorityMap is used to remap the priority of levels.
+	// The priority of a level is left to be unchanged if it is not in the map.
+	// The default mapping is as the follows:
+	//   Trace: PriDebug
+	//   Debug: PriDebug
+	//   Info:  PriInfo
+	//   Warn:  PriWarning
+	//   Error: PriErr
+	//   Fatal: PriCrit
+	PriorityMap map[iface.Level]Priority
+	// TempDir is the directory to create temporary files in for entries too
+	// large to be sent in a datagram. The file descriptors of them are passed to
+	// journald instead.
+	// If TempDir is not specified, "/dev/shm" is used if it exists, otherwise
+	// os.TempDir() is used.
+	TempDir string
+	// ErrorHandler will be called when an error occurs if it is not nil.
+	ErrorHandler writer.ErrorHandler
+}
+
+func (config *Config) setDefaults() {
+	if config.Addr == "" {
+		config.Addr = "/run/systemd/journal/socket"
+	}
+	if config.Identifier == "" {
+		config.Identifier = filepath.Base(os.Args[0])
+	}
+	if config.TempDir == "" {
+		config.TempDir = os.TempDir()
+		if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+			config.TempDir = "/dev/shm"
+		}
+	}
+}
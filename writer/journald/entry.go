@@ -0,0 +1,99 @@
+package journald
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+const maxFieldName = 64
+
+// appendField appends a field in the native protocol of journald. A value
+// with line feeds is prefixed with its length in little endian uint64.
+func appendField(buf []byte, name string, value []byte) []byte {
+	buf = append(buf, name...)
+	for _, b := range value {
+		if b == '\n' {
+			buf = append(buf, '\n')
+			var size [8]byte
+			binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+			buf = append(buf, size[:]...)
+			buf = append(buf, value...)
+			return append(buf, '\n')
+		}
+	}
+	buf = append(buf, '=')
+	buf = append(buf, value...)
+	return append(buf, '\n')
+}
+
+func appendStringField(buf []byte, name, value string) []byte {
+	if strings.IndexByte(value, '\n') < 0 {
+		buf = append(buf, name...)
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+		return append(buf, '\n')
+	}
+	return appendField(buf, name, []byte(value))
+}
+
+func appendEntry(buf []byte, priority Priority, identifier string, msg []byte,
+	record *iface.Record) []byte {
+
+	buf = appendField(buf, "MESSAGE", trimNewline(msg))
+	buf = append(buf, "PRIORITY="...)
+	buf = strconv.AppendInt(buf, int64(priority), 10)
+	buf = append(buf, '\n')
+	buf = appendStringField(buf, "SYSLOG_IDENTIFIER", identifier)
+	if record.File != "" {
+		buf = appendStringField(buf, "CODE_FILE", record.File)
+		buf = append(buf, "CODE_LINE="...)
+		buf = strconv.AppendInt(buf, int64(record.Line), 10)
+		buf = append(buf, '\n')
+	}
+	if record.Func != "" {
+		fn := record.Func
+		if record.Pkg != "" {
+			fn = record.Pkg + "." + fn
+		}
+		buf = appendStringField(buf, "CODE_FUNC", fn)
+	}
+	for _, ctx := range record.Aux.Contexts {
+		if name := fieldName(ctx.Key); name != "" {
+			buf = appendStringField(buf, name, ctx.Value)
+		}
+	}
+	return buf
+}
+
+// fieldName converts the key of a context to a journal field name, which
+// consists of uppercase letters, digits and underscores only and does NOT
+// start with an underscore or a digit. Bytes other than them are replaced
+// with underscores. It returns an empty string if nothing is left.
+func fieldName(key string) string {
+	name := make([]byte, 0, len(key))
+	for i := 0; i < len(key) && len(name) < maxFieldName; i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			c = '_'
+		}
+		if len(name) == 0 && (c == '_' || c >= '0' && c <= '9') {
+			continue
+		}
+		name = append(name, c)
+	}
+	return string(name)
+}
+
+func trimNewline(bs []byte) []byte {
+	for len(bs) > 0 && (bs[len(bs)-1] == '\n' || bs[len(bs)-1] == '\r') {
+		bs = bs[:len(bs)-1]
+	}
+	return bs
+}
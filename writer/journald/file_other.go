@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !solaris
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!solaris
+
+package journald
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+func sendFile(*net.UnixConn, *os.File) error {
+	return errors.New("passing file descriptors is unsupported on this platform")
+}
+
+// isTooLarge always reports false, since a too large entry can NOT be sent with
+// a file descriptor on this platform anyway.
+func isTooLarge(error) bool {
+	return false
+}
@@ -0,0 +1,36 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd || solaris
+// +build linux darwin dragonfly freebsd netbsd openbsd solaris
+
+package journald
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// sendFile sends the file descriptor of the file with SCM_RIGHTS. It calls
+// sendmsg directly because the connection is connected.
+func sendFile(conn *net.UnixConn, file *os.File) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(file.Fd()))
+	var sendErr error
+	err = rawConn.Write(func(fd uintptr) bool {
+		sendErr = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return sendErr != syscall.EAGAIN
+	})
+	if err != nil {
+		return err
+	}
+	return sendErr
+}
+
+// isTooLarge reports whether the err is caused by an entry too large to be sent
+// in a datagram.
+func isTooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
@@ -0,0 +1,85 @@
+package journald
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+)
+
+type journal struct {
+	addr    *net.UnixAddr
+	tempDir string
+	conn    *net.UnixConn
+}
+
+func journalDial(addr, tempDir string) (*journal, error) {
+	jnl := &journal{
+		addr:    &net.UnixAddr{Name: addr, Net: "unixgram"},
+		tempDir: tempDir,
+	}
+	if err := jnl.connect(); err != nil {
+		return nil, err
+	}
+	return jnl, nil
+}
+
+// Write sends the entry in a datagram. If the entry is too large, it is written
+// to an unlinked temporary file and the file descriptor is sent instead.
+func (jnl *journal) Write(entry []byte) error {
+	if jnl.conn == nil {
+		if err := jnl.connect(); err != nil {
+			return err
+		}
+	}
+	_, err := jnl.conn.Write(entry)
+	if err == nil {
+		return nil
+	}
+	if isTooLarge(err) {
+		return jnl.writeFile(entry)
+	}
+	// journald may have been restarted, reconnect once
+	jnl.Close()
+	if err := jnl.connect(); err != nil {
+		return err
+	}
+	if _, err := jnl.conn.Write(entry); err != nil {
+		jnl.Close()
+		return err
+	}
+	return nil
+}
+
+func (jnl *journal) Close() error {
+	if jnl.conn != nil {
+		err := jnl.conn.Close()
+		jnl.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (jnl *journal) connect() error {
+	conn, err := net.DialUnix("unixgram", nil, jnl.addr)
+	if err != nil {
+		return err
+	}
+	jnl.conn = conn
+	return nil
+}
+
+func (jnl *journal) writeFile(entry []byte) error {
+	file, err := ioutil.TempFile(jnl.tempDir, "gxlog-journal-")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// journald only accepts the file if it is unlinked
+	if err := os.Remove(file.Name()); err != nil {
+		return err
+	}
+	if _, err := file.Write(entry); err != nil {
+		return err
+	}
+	return sendFile(jnl.conn, file)
+}
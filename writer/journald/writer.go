@@ -0,0 +1,145 @@
+// Package journald implements a journald writer which implements the Writer.
+// It sends structured entries to systemd-journald with its native protocol.
+//
+// Each log becomes an entry with the following fields:
+//
+//	MESSAGE           the formatted log without trailing line feeds
+//	PRIORITY          the priority mapped from the level
+//	SYSLOG_IDENTIFIER the identifier of the Writer
+//	CODE_FILE         the file of the log
+//	CODE_LINE         the line of the log
+//	CODE_FUNC         the pkg and func of the log, e.g. main.main
+//
+// and a field for each context, whose name is the key of the context in
+// uppercase, with bytes other than letters, digits and underscores replaced
+// with underscores and the leading underscores and digits removed, e.g.
+// the context "req-id" becomes the field REQ_ID. Contexts with empty names
+// are ignored.
+package journald
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer"
+)
+
+// A Writer implements the interface iface.Writer.
+//
+// All methods of a Writer are concurrency safe.
+// A Writer MUST be created with Open.
+type Writer struct {
+	identifier   string
+	errorHandler writer.ErrorHandler
+
+	priorities []Priority
+	jnl        *journal
+	buf        []byte
+
+	lock sync.Mutex
+}
+
+// Open creates a new Writer with the config.
+func Open(config Config) (*Writer, error) {
+	config.setDefaults()
+	jnl, err := journalDial(config.Addr, config.TempDir)
+	if err != nil {
+		return nil, fmt.Errorf("writer/journald.Open: %v", err)
+	}
+	priorities := []Priority{
+		iface.Trace: PriDebug,
+		iface.Debug: PriDebug,
+		iface.Info:  PriInfo,
+		iface.Warn:  PriWarning,
+		iface.Error: PriErr,
+		iface.Fatal: PriCrit,
+	}
+	writer := &Writer{
+		identifier:   config.Identifier,
+		errorHandler: config.ErrorHandler,
+		priorities:   priorities,
+		jnl:          jnl,
+	}
+	writer.MapPriorities(config.PriorityMap)
+	return writer, nil
+}
+
+// Close closes the Writer.
+func (writer *Writer) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if err := writer.jnl.Close(); err != nil {
+		return fmt.Errorf("writer/journald.Close: %v", err)
+	}
+	return nil
+}
+
+// Write implements the interface Writer. It writes logs to journald.
+func (writer *Writer) Write(bs []byte, record *iface.Record) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if err := writer.send(bs, record); err != nil && writer.errorHandler != nil {
+		writer.errorHandler(bs, record, err)
+	}
+}
+
+// Send implements the interface writer.Sender. It does the same with Write
+// except that it returns the error instead of calling the error handler.
+func (writer *Writer) Send(bs []byte, record *iface.Record) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.send(bs, record)
+}
+
+// Identifier returns the identifier of the Writer.
+func (writer *Writer) Identifier() string {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.identifier
+}
+
+// SetIdentifier sets the identifier of the Writer.
+func (writer *Writer) SetIdentifier(identifier string) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.identifier = identifier
+}
+
+// ErrorHandler returns the error handler of the Writer.
+func (writer *Writer) ErrorHandler() writer.ErrorHandler {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.errorHandler
+}
+
+// SetErrorHandler sets the error handler of the Writer.
+func (writer *Writer) SetErrorHandler(handler writer.ErrorHandler) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.errorHandler = handler
+}
+
+// MapPriorities maps the priority of levels according to the priorityMap.
+// The priority of a level is left to be unchanged if it is not in the map.
+func (writer *Writer) MapPriorities(priorityMap map[iface.Level]Priority) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	for level, priority := range priorityMap {
+		writer.priorities[level] = priority
+	}
+}
+
+func (writer *Writer) send(bs []byte, record *iface.Record) error {
+	priority := writer.priorities[record.Level]
+	writer.buf = appendEntry(writer.buf[:0], priority, writer.identifier, bs, record)
+	return writer.jnl.Write(writer.buf)
+}
@@ -0,0 +1,175 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd || solaris
+// +build linux darwin dragonfly freebsd netbsd openbsd solaris
+
+package journald_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/journald"
+)
+
+var tmplRecord = iface.Record{
+	Time:  time.Now(),
+	Level: iface.Warn,
+	File:  "/home/test/main.go",
+	Line:  10,
+	Pkg:   "main",
+	Func:  "main",
+	Msg:   "testing",
+	Aux: iface.Auxiliary{
+		Contexts: []iface.Context{
+			{Key: "req-id", Value: "abc"},
+			{Key: "_trusted", Value: "no"},
+			{Key: "stack", Value: "a\nb"},
+			{Key: "!!", Value: "ignored"},
+		},
+	},
+}
+
+func TestWrite(t *testing.T) {
+	conn, addr := listen(t)
+	defer conn.Close()
+
+	wt, err := journald.Open(journald.Config{
+		Addr:       addr,
+		Identifier: "app",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+
+	if err := wt.Send([]byte("testing\n"), &tmplRecord); err != nil {
+		t.Fatal(err)
+	}
+	entry, _ := readEntry(t, conn)
+	expect := map[string]string{
+		"MESSAGE":           "testing",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "app",
+		"CODE_FILE":         "/home/test/main.go",
+		"CODE_LINE":         "10",
+		"CODE_FUNC":         "main.main",
+		"REQ_ID":            "abc",
+		"TRUSTED":           "no",
+		"STACK":             "a\nb",
+	}
+	checkEntry(t, entry, expect)
+}
+
+func TestLargeEntry(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("passing file descriptors is tested on linux only")
+	}
+	conn, addr := listen(t)
+	defer conn.Close()
+
+	wt, err := journald.Open(journald.Config{
+		Addr:       addr,
+		Identifier: "app",
+		TempDir:    t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+
+	msg := strings.Repeat("x", 4<<20)
+	record := tmplRecord
+	record.Aux = iface.Auxiliary{}
+	if err := wt.Send([]byte(msg), &record); err != nil {
+		t.Fatal(err)
+	}
+	entry, fromFile := readEntry(t, conn)
+	if !fromFile {
+		t.Error("TestLargeEntry: the entry is NOT passed with a file")
+	}
+	if entry["MESSAGE"] != msg {
+		t.Errorf("TestLargeEntry: MESSAGE of %d bytes, expect %d bytes",
+			len(entry["MESSAGE"]), len(msg))
+	}
+}
+
+func listen(t *testing.T) (*net.UnixConn, string) {
+	addr := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, addr
+}
+
+func readEntry(t *testing.T, conn *net.UnixConn) (map[string]string, bool) {
+	buf := make([]byte, 256<<10)
+	oob := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oobn == 0 {
+		return parseEntry(t, buf[:n]), false
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := os.NewFile(uintptr(fds[0]), "entry")
+	defer file.Close()
+	if _, err := file.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseEntry(t, data), true
+}
+
+func parseEntry(t *testing.T, data []byte) map[string]string {
+	entry := make(map[string]string)
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			t.Fatalf("parseEntry: missing line feed in %q", data)
+		}
+		line := data[:i]
+		data = data[i+1:]
+		if j := bytes.IndexByte(line, '='); j >= 0 {
+			entry[string(line[:j])] = string(line[j+1:])
+			continue
+		}
+		size := int(binary.LittleEndian.Uint64(data))
+		entry[string(line)] = string(data[8 : 8+size])
+		data = data[8+size+1:]
+	}
+	return entry
+}
+
+func checkEntry(t *testing.T, entry, expect map[string]string) {
+	if len(entry) != len(expect) {
+		t.Errorf("checkEntry: output: %q, expect: %q", entry, expect)
+		return
+	}
+	for name, value := range expect {
+		if entry[name] != value {
+			t.Errorf("checkEntry: %s: output: %q, expect: %q", name, entry[name], value)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package gelf
+
+import (
+	"compress/flate"
+	"errors"
+
+	"github.com/fufuok/gxlog/writer"
+)
+
+// The Compression defines the type of compression of GELF messages.
+type Compression int
+
+// All available compressions here.
+const (
+	// Messages are compressed with gzip.
+	Gzip Compression = iota
+	// Messages are compressed with zlib.
+	Zlib
+	// Messages are NOT compressed.
+	NoCompression
+)
+
+const (
+	maxChunks    = 128
+	chunkHdrSize = 12
+)
+
+// A Config is used to configure a GELF udp writer.
+type Config struct {
+	// Addr is the address of the GELF udp input of Graylog. It will be passed
+	// to net.Dial. It MUST be specified.
+	Addr string
+	// Compression specifies how messages are compressed.
+	// If Compression is not specified, Gzip is used.
+	Compression Compression
+	// CompressionLevel is the level of compression, see compress/flate.
+	// If CompressionLevel is not specified, flate.BestSpeed is used.
+	CompressionLevel int
+	// ChunkSize is the max size of the payload of a datagram. A message larger
+	// than it is sent in chunks, at most 128 chunks.
+	// If ChunkSize is not specified, 1420 is used, which fits in an ethernet
+	// frame. ChunkSize must be between 13 and 65507.
+	ChunkSize int
+	// ErrorHandler will be called when an error occurs if it is not nil.
+	ErrorHandler writer.ErrorHandler
+}
+
+func (config *Config) setDefaults() {
+	if config.CompressionLevel == 0 {
+		config.CompressionLevel = flate.BestSpeed
+	}
+	if config.ChunkSize == 0 {
+		config.ChunkSize = 1420
+	}
+}
+
+func (config *Config) check() error {
+	if config.Addr == "" {
+		return errors.New("Config.Addr must be specified")
+	}
+	if config.Compression < Gzip || config.Compression > NoCompression {
+		return errors.New("Config.Compression is invalid")
+	}
+	if config.CompressionLevel < flate.HuffmanOnly ||
+		config.CompressionLevel > flate.BestCompression {
+		return errors.New("Config.CompressionLevel is invalid")
+	}
+	if config.ChunkSize <= chunkHdrSize || config.ChunkSize > 65507 {
+		return errors.New("Config.ChunkSize must be between 13 and 65507")
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Package gelf implements a GELF udp writer which implements the Writer.
+//
+// The GELF udp writer sends each log, which SHOULD be formatted by a gelf
+// formatter, to Graylog as a compressed GELF message. A message larger than the
+// chunk size is split into GELF chunks. Delivery is NOT guaranteed.
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+var chunkMagic = []byte{0x1e, 0x0f}
+
+// A Writer implements the interface iface.Writer and writer.Sender.
+//
+// All methods of a Writer are concurrency safe.
+// A Writer MUST be created with Open.
+type Writer struct {
+	config     Config
+	conn       net.Conn
+	compressed bytes.Buffer
+	compressor io.WriteCloser
+	chunk      []byte
+	rand       *rand.Rand
+
+	lock sync.Mutex
+}
+
+// Open creates a new Writer with the config.
+func Open(config Config) (*Writer, error) {
+	config.setDefaults()
+	if err := config.check(); err != nil {
+		return nil, fmt.Errorf("writer/net/gelf.Open: %v", err)
+	}
+	conn, err := net.Dial("udp", config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("writer/net/gelf.Open: %v", err)
+	}
+	writer := &Writer{
+		config: config,
+		conn:   conn,
+		chunk:  make([]byte, 0, config.ChunkSize),
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	return writer, nil
+}
+
+// Close closes the Writer.
+func (writer *Writer) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if err := writer.conn.Close(); err != nil {
+		return fmt.Errorf("writer/net/gelf.Close: %v", err)
+	}
+	return nil
+}
+
+// Write implements the interface Writer. It sends logs to Graylog.
+func (writer *Writer) Write(bs []byte, record *iface.Record) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	err := writer.send(bs)
+	if err != nil && writer.config.ErrorHandler != nil {
+		writer.config.ErrorHandler(bs, record, err)
+	}
+}
+
+// Send implements the interface writer.Sender. It does the same with Write
+// except that it returns the error instead of calling the error handler.
+func (writer *Writer) Send(bs []byte, record *iface.Record) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.send(bs)
+}
+
+func (writer *Writer) send(bs []byte) error {
+	msg, err := writer.compress(bytes.TrimRight(bs, "\n"))
+	if err != nil {
+		return err
+	}
+	size := writer.config.ChunkSize
+	if len(msg) <= size {
+		_, err := writer.conn.Write(msg)
+		return err
+	}
+	payloadSize := size - chunkHdrSize
+	count := (len(msg) + payloadSize - 1) / payloadSize
+	if count > maxChunks {
+		return fmt.Errorf("message size %d exceeds %d chunks of size %d",
+			len(msg), maxChunks, size)
+	}
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], writer.rand.Uint64())
+	for seq := 0; seq < count; seq++ {
+		payload := msg
+		if len(payload) > payloadSize {
+			payload = payload[:payloadSize]
+		}
+		msg = msg[len(payload):]
+		chunk := append(writer.chunk[:0], chunkMagic...)
+		chunk = append(chunk, id[:]...)
+		chunk = append(chunk, byte(seq), byte(count))
+		chunk = append(chunk, payload...)
+		if _, err := writer.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (writer *Writer) compress(bs []byte) ([]byte, error) {
+	if writer.config.Compression == NoCompression {
+		return bs, nil
+	}
+	writer.compressed.Reset()
+	if err := writer.resetCompressor(); err != nil {
+		return nil, err
+	}
+	if _, err := writer.compressor.Write(bs); err != nil {
+		return nil, err
+	}
+	if err := writer.compressor.Close(); err != nil {
+		return nil, err
+	}
+	return writer.compressed.Bytes(), nil
+}
+
+func (writer *Writer) resetCompressor() error {
+	level := writer.config.CompressionLevel
+	switch compressor := writer.compressor.(type) {
+	case *gzip.Writer:
+		compressor.Reset(&writer.compressed)
+	case *zlib.Writer:
+		compressor.Reset(&writer.compressed)
+	default:
+		var err error
+		if writer.config.Compression == Zlib {
+			writer.compressor, err = zlib.NewWriterLevel(&writer.compressed, level)
+		} else {
+			writer.compressor, err = gzip.NewWriterLevel(&writer.compressed, level)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package gelf_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/hex"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/net/gelf"
+)
+
+func TestGzip(t *testing.T) {
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	wt, err := gelf.Open(gelf.Config{Addr: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+
+	msg := `{"version":"1.1","host":"host","short_message":"testing"}`
+	if err := wt.Send([]byte(msg+"\n"), &iface.Record{}); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(readDatagram(t, conn)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != msg {
+		t.Errorf("TestGzip:\noutput: %q\nexpect: %q", output, msg)
+	}
+}
+
+func TestChunking(t *testing.T) {
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	wt, err := gelf.Open(gelf.Config{
+		Addr:        conn.LocalAddr().String(),
+		Compression: gelf.Zlib,
+		ChunkSize:   512,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+
+	// random bytes are hardly compressible
+	random := make([]byte, 2000)
+	rand.Read(random)
+	msg := `{"short_message":"` + hex.EncodeToString(random) + `"}`
+	if err := wt.Send([]byte(msg), &iface.Record{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var id []byte
+	var payloads [][]byte
+	for count := 1; len(payloads) < count; {
+		chunk := readDatagram(t, conn)
+		if len(chunk) > 512 || chunk[0] != 0x1e || chunk[1] != 0x0f {
+			t.Fatalf("TestChunking: invalid chunk header: %x", chunk[:12])
+		}
+		if id == nil {
+			id = chunk[2:10]
+			count = int(chunk[11])
+			payloads = make([][]byte, 0, count)
+		}
+		if !bytes.Equal(chunk[2:10], id) || int(chunk[10]) != len(payloads) {
+			t.Fatalf("TestChunking: unexpected chunk header: %x", chunk[:12])
+		}
+		payloads = append(payloads, chunk[12:])
+	}
+	if len(payloads) < 2 {
+		t.Fatalf("TestChunking: the message is NOT chunked")
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(bytes.Join(payloads, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != msg {
+		t.Errorf("TestChunking: output of %d bytes, expect %d bytes", len(output), len(msg))
+	}
+
+	big := make([]byte, 128*500+1)
+	rand.Read(big)
+	if err := wt.Send(big, &iface.Record{}); err == nil {
+		t.Error("TestChunking: expect an error for too many chunks")
+	}
+}
+
+func listenUDP(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func readDatagram(t *testing.T, conn net.PacketConn) []byte {
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf[:n]
+}
@@ -0,0 +1,82 @@
+package tcp
+
+import (
+	"crypto/tls"
+	"errors"
+	"time"
+
+	"github.com/fufuok/gxlog/writer"
+)
+
+// The Framing defines the type of framing of logs in a tcp stream.
+type Framing int
+
+// All available framings here.
+const (
+	// Each log is terminated with a '\n' unless it already ends with one.
+	NewlineFraming Framing = iota
+	// Each log is prefixed with its length as a 4-byte big-endian integer.
+	LengthPrefixFraming
+)
+
+// A Config is used to configure a tcp client writer.
+type Config struct {
+	// Addr is the address of the collector that logs are pushed to. It will be
+	// passed to net.Dial. It MUST be specified.
+	Addr string
+	// Framing specifies how logs are delimited in the tcp stream.
+	// If Framing is not specified, NewlineFraming is used.
+	Framing Framing
+	// TLSConfig is used to dial with TLS if it is not nil. If its ServerName
+	// is empty, the host of Addr is used.
+	TLSConfig *tls.Config
+	// DialTimeout is the timeout of dialing.
+	// If DialTimeout is not specified, (5 * time.Second) is used.
+	DialTimeout time.Duration
+	// WriteTimeout is the timeout of writing a log. A write that times out
+	// causes a reconnection.
+	// If WriteTimeout is not specified, (5 * time.Second) is used.
+	WriteTimeout time.Duration
+	// MinBackoff is the interval before the first reconnection after a failure.
+	// It doubles after each failed reconnection until it reaches MaxBackoff.
+	// Logs written during the interval are dropped and reported to the
+	// ErrorHandler. Wrap the writer with a retry writer to avoid losing them.
+	// If MinBackoff is not specified, (100 * time.Millisecond) is used.
+	MinBackoff time.Duration
+	// MaxBackoff is the max interval between reconnections.
+	// If MaxBackoff is not specified, (30 * time.Second) is used.
+	MaxBackoff time.Duration
+	// ErrorHandler will be called when an error occurs if it is not nil.
+	ErrorHandler writer.ErrorHandler
+}
+
+func (config *Config) setDefaults() {
+	if config.DialTimeout == 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	if config.WriteTimeout == 0 {
+		config.WriteTimeout = 5 * time.Second
+	}
+	if config.MinBackoff == 0 {
+		config.MinBackoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+}
+
+func (config *Config) check() error {
+	if config.Addr == "" {
+		return errors.New("Config.Addr must be specified")
+	}
+	if config.Framing != NewlineFraming && config.Framing != LengthPrefixFraming {
+		return errors.New("Config.Framing is invalid")
+	}
+	if config.DialTimeout < 0 || config.WriteTimeout < 0 {
+		return errors.New("Config.DialTimeout and Config.WriteTimeout must NOT be negative")
+	}
+	if config.MinBackoff < 0 || config.MaxBackoff < config.MinBackoff {
+		return errors.New("Config.MinBackoff or Config.MaxBackoff is invalid")
+	}
+	return nil
+}
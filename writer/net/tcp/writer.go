@@ -0,0 +1,152 @@
+// Package tcp implements a tcp client writer which implements the Writer.
+//
+// The tcp client writer pushes logs to a remote collector. It reconnects with
+// exponential backoff when the connection breaks. Unlike the tcp socket writer,
+// which serves logs to whoever connects for log watching, it aims at log
+// transmission.
+package tcp
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/internal/backoff"
+)
+
+var errBackoff = errors.New("waiting to reconnect")
+
+// A Writer implements the interface iface.Writer and writer.Sender.
+//
+// All methods of a Writer are concurrency safe.
+// A Writer MUST be created with Open.
+type Writer struct {
+	config Config
+
+	conn    net.Conn
+	backoff *backoff.Backoff
+	buf     []byte
+
+	lock sync.Mutex
+}
+
+// Open creates a new Writer with the config. It does NOT dial until the first
+// log is written.
+func Open(config Config) (*Writer, error) {
+	config.setDefaults()
+	if err := config.check(); err != nil {
+		return nil, fmt.Errorf("writer/net/tcp.Open: %v", err)
+	}
+	if config.TLSConfig != nil && config.TLSConfig.ServerName == "" {
+		host, _, err := net.SplitHostPort(config.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("writer/net/tcp.Open: %v", err)
+		}
+		config.TLSConfig = config.TLSConfig.Clone()
+		config.TLSConfig.ServerName = host
+	}
+	return &Writer{
+		config:  config,
+		backoff: backoff.New(config.MinBackoff, config.MaxBackoff),
+	}, nil
+}
+
+// Close closes the Writer.
+func (writer *Writer) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if err := writer.closeConn(); err != nil {
+		return fmt.Errorf("writer/net/tcp.Close: %v", err)
+	}
+	return nil
+}
+
+// Write implements the interface Writer. It writes logs to the collector.
+func (writer *Writer) Write(bs []byte, record *iface.Record) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	err := writer.send(bs)
+	if err != nil && writer.config.ErrorHandler != nil {
+		writer.config.ErrorHandler(bs, record, err)
+	}
+}
+
+// Send implements the interface writer.Sender. It does the same with Write
+// except that it returns the error instead of calling the error handler.
+func (writer *Writer) Send(bs []byte, record *iface.Record) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.send(bs)
+}
+
+func (writer *Writer) send(bs []byte) error {
+	if writer.conn == nil {
+		if err := writer.connect(); err != nil {
+			return err
+		}
+	}
+	writer.buf = frame(writer.buf[:0], bs, writer.config.Framing)
+	writer.conn.SetWriteDeadline(time.Now().Add(writer.config.WriteTimeout))
+	if _, err := writer.conn.Write(writer.buf); err != nil {
+		writer.closeConn()
+		writer.backoff.Fail(time.Now())
+		return err
+	}
+	return nil
+}
+
+func (writer *Writer) connect() error {
+	now := time.Now()
+	if !writer.backoff.Ready(now) {
+		return errBackoff
+	}
+	dialer := &net.Dialer{Timeout: writer.config.DialTimeout}
+	var conn net.Conn
+	var err error
+	if writer.config.TLSConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", writer.config.Addr,
+			writer.config.TLSConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", writer.config.Addr)
+	}
+	if err != nil {
+		writer.backoff.Fail(now)
+		return err
+	}
+	writer.backoff.Reset()
+	writer.conn = conn
+	return nil
+}
+
+func (writer *Writer) closeConn() error {
+	if writer.conn != nil {
+		err := writer.conn.Close()
+		writer.conn = nil
+		return err
+	}
+	return nil
+}
+
+func frame(buf, bs []byte, framing Framing) []byte {
+	switch framing {
+	case LengthPrefixFraming:
+		var prefix [4]byte
+		binary.BigEndian.PutUint32(prefix[:], uint32(len(bs)))
+		buf = append(buf, prefix[:]...)
+		buf = append(buf, bs...)
+	default:
+		buf = append(buf, bs...)
+		if len(bs) == 0 || bs[len(bs)-1] != '\n' {
+			buf = append(buf, '\n')
+		}
+	}
+	return buf
+}
@@ -0,0 +1,200 @@
+package tcp_test
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/net/tcp"
+)
+
+var tmplRecord = &iface.Record{Level: iface.Info}
+
+func TestNewlineFraming(t *testing.T) {
+	listener := listen(t, nil)
+	defer listener.Close()
+
+	wt := open(t, tcp.Config{Addr: listener.Addr().String()})
+	defer wt.Close()
+
+	wt.Write([]byte("first"), tmplRecord)
+	wt.Write([]byte("second\n"), tmplRecord)
+
+	reader := bufio.NewReader(accept(t, listener))
+	for _, expect := range []string{"first\n", "second\n"} {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != expect {
+			t.Errorf("TestNewlineFraming:\noutput: %q\nexpect: %q", line, expect)
+		}
+	}
+}
+
+func TestLengthPrefixFraming(t *testing.T) {
+	listener := listen(t, nil)
+	defer listener.Close()
+
+	wt := open(t, tcp.Config{
+		Addr:    listener.Addr().String(),
+		Framing: tcp.LengthPrefixFraming,
+	})
+	defer wt.Close()
+
+	wt.Write([]byte("multi\nline"), tmplRecord)
+
+	output := readFrame(t, accept(t, listener))
+	if output != "multi\nline" {
+		t.Errorf("TestLengthPrefixFraming:\noutput: %q\nexpect: %q", output, "multi\nline")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	listener := listen(t, nil)
+	defer listener.Close()
+
+	wt := open(t, tcp.Config{
+		Addr:       listener.Addr().String(),
+		Framing:    tcp.LengthPrefixFraming,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Millisecond,
+	})
+	defer wt.Close()
+
+	if err := wt.Send([]byte("before"), tmplRecord); err != nil {
+		t.Fatal(err)
+	}
+	conn := accept(t, listener)
+	readFrame(t, conn)
+	conn.Close()
+
+	// the first writes after the peer closes may still succeed
+	deadline := time.Now().Add(5 * time.Second)
+	for wt.Send([]byte("probe"), tmplRecord) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("the broken connection is not detected")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if err := wt.Send([]byte("after"), tmplRecord); err != nil {
+		t.Fatal(err)
+	}
+	output := readFrame(t, accept(t, listener))
+	if output != "after" {
+		t.Errorf("TestReconnect:\noutput: %q\nexpect: %q", output, "after")
+	}
+}
+
+func TestTLS(t *testing.T) {
+	cert, pool := makeCert(t)
+	listener := listen(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+	defer listener.Close()
+
+	wt := open(t, tcp.Config{
+		Addr:      listener.Addr().String(),
+		TLSConfig: &tls.Config{RootCAs: pool},
+	})
+	defer wt.Close()
+
+	chanLine := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			chanLine <- err.Error()
+			return
+		}
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		chanLine <- line
+	}()
+	if err := wt.Send([]byte("secure"), tmplRecord); err != nil {
+		t.Fatal(err)
+	}
+	if line := <-chanLine; line != "secure\n" {
+		t.Errorf("TestTLS:\noutput: %q\nexpect: %q", line, "secure\n")
+	}
+}
+
+func open(t *testing.T, config tcp.Config) *tcp.Writer {
+	wt, err := tcp.Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wt
+}
+
+func listen(t *testing.T, config *tls.Config) net.Listener {
+	var listener net.Listener
+	var err error
+	if config != nil {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", config)
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listener
+}
+
+func accept(t *testing.T, listener net.Listener) net.Conn {
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func readFrame(t *testing.T, conn net.Conn) string {
+	var prefix [4]byte
+	if _, err := io.ReadFull(conn, prefix[:]); err != nil {
+		t.Fatal(err)
+	}
+	frame := make([]byte, binary.BigEndian.Uint32(prefix[:]))
+	if _, err := io.ReadFull(conn, frame); err != nil {
+		t.Fatal(err)
+	}
+	return string(frame)
+}
+
+func makeCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
@@ -0,0 +1,56 @@
+package udp
+
+import (
+	"errors"
+
+	"github.com/fufuok/gxlog/writer"
+)
+
+// The Oversize defines the type of policy for logs that exceed the max
+// datagram size.
+type Oversize int
+
+// All available policies here.
+const (
+	// The log is truncated to the max datagram size.
+	Truncate Oversize = iota
+	// The log is dropped and reported to the ErrorHandler.
+	Drop
+	// The log is split into as many datagrams as needed.
+	Split
+)
+
+// A Config is used to configure a udp client writer.
+type Config struct {
+	// Addr is the address of the collector that logs are pushed to. It will be
+	// passed to net.Dial. It MUST be specified.
+	Addr string
+	// MaxDatagramSize is the max size of the payload of a datagram.
+	// If MaxDatagramSize is not specified, 1472 is used, which fits in an
+	// ethernet frame.
+	MaxDatagramSize int
+	// Oversize specifies what to do with a log that exceeds MaxDatagramSize.
+	// If Oversize is not specified, Truncate is used.
+	Oversize Oversize
+	// ErrorHandler will be called when an error occurs if it is not nil.
+	ErrorHandler writer.ErrorHandler
+}
+
+func (config *Config) setDefaults() {
+	if config.MaxDatagramSize == 0 {
+		config.MaxDatagramSize = 1472
+	}
+}
+
+func (config *Config) check() error {
+	if config.Addr == "" {
+		return errors.New("Config.Addr must be specified")
+	}
+	if config.MaxDatagramSize < 0 || config.MaxDatagramSize > 65507 {
+		return errors.New("Config.MaxDatagramSize must be between 1 and 65507")
+	}
+	if config.Oversize < Truncate || config.Oversize > Split {
+		return errors.New("Config.Oversize is invalid")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Package udp implements a udp client writer which implements the Writer.
+//
+// The udp client writer pushes each log to a remote collector as a datagram.
+// Delivery is NOT guaranteed, use a tcp client writer if logs must NOT be lost.
+package udp
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+// A Writer implements the interface iface.Writer and writer.Sender.
+//
+// All methods of a Writer are concurrency safe.
+// A Writer MUST be created with Open.
+type Writer struct {
+	config Config
+	conn   net.Conn
+
+	lock sync.Mutex
+}
+
+// Open creates a new Writer with the config.
+func Open(config Config) (*Writer, error) {
+	config.setDefaults()
+	if err := config.check(); err != nil {
+		return nil, fmt.Errorf("writer/net/udp.Open: %v", err)
+	}
+	conn, err := net.Dial("udp", config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("writer/net/udp.Open: %v", err)
+	}
+	return &Writer{config: config, conn: conn}, nil
+}
+
+// Close closes the Writer.
+func (writer *Writer) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if err := writer.conn.Close(); err != nil {
+		return fmt.Errorf("writer/net/udp.Close: %v", err)
+	}
+	return nil
+}
+
+// Write implements the interface Writer. It writes logs to the collector.
+func (writer *Writer) Write(bs []byte, record *iface.Record) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	err := writer.send(bs)
+	if err != nil && writer.config.ErrorHandler != nil {
+		writer.config.ErrorHandler(bs, record, err)
+	}
+}
+
+// Send implements the interface writer.Sender. It does the same with Write
+// except that it returns the error instead of calling the error handler.
+func (writer *Writer) Send(bs []byte, record *iface.Record) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.send(bs)
+}
+
+func (writer *Writer) send(bs []byte) error {
+	size := writer.config.MaxDatagramSize
+	if len(bs) > size {
+		switch writer.config.Oversize {
+		case Drop:
+			return fmt.Errorf("log size %d exceeds the max datagram size %d",
+				len(bs), size)
+		case Split:
+			for len(bs) > size {
+				if _, err := writer.conn.Write(bs[:size]); err != nil {
+					return err
+				}
+				bs = bs[size:]
+			}
+		default:
+			bs = bs[:size]
+		}
+	}
+	_, err := writer.conn.Write(bs)
+	return err
+}
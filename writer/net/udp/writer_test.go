@@ -0,0 +1,64 @@
+package udp_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/net/udp"
+)
+
+var tmplRecord = &iface.Record{Level: iface.Info}
+
+func TestOversize(t *testing.T) {
+	tests := []struct {
+		oversize udp.Oversize
+		expect   []string
+		fails    bool
+	}{
+		{udp.Truncate, []string{"01234"}, false},
+		{udp.Split, []string{"01234", "56789", "ab"}, false},
+		{udp.Drop, nil, true},
+	}
+	for _, test := range tests {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		wt, err := udp.Open(udp.Config{
+			Addr:            conn.LocalAddr().String(),
+			MaxDatagramSize: 5,
+			Oversize:        test.oversize,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = wt.Send([]byte("0123456789ab"), tmplRecord)
+		if (err != nil) != test.fails {
+			t.Errorf("TestOversize: policy %d, unexpected error: %v", test.oversize, err)
+		}
+		wt.Send([]byte("end"), tmplRecord)
+
+		var output []string
+		buf := make([]byte, 64)
+		for {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(buf[:n]) == "end" {
+				break
+			}
+			output = append(output, string(buf[:n]))
+		}
+		if strings.Join(output, "|") != strings.Join(test.expect, "|") {
+			t.Errorf("TestOversize: policy %d\noutput: %q\nexpect: %q",
+				test.oversize, output, test.expect)
+		}
+		wt.Close()
+		conn.Close()
+	}
+}
@@ -0,0 +1,80 @@
+package retry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fufuok/gxlog/writer"
+)
+
+// A Config is used to configure a retry writer.
+type Config struct {
+	// Dir is the directory where spooled logs are stored. Logs left in it will
+	// be replayed when a retry writer is opened with the same Dir again.
+	// Shell expansion is NOT supported.
+	// If Dir is not specified, <os.TempDir()>/gxlog/spool/<filepath.Base(os.Args[0])>
+	// is used.
+	Dir string
+	// DirPerm represents the permission bits of created directories.
+	// If DirPerm is not specified, 0700 is used.
+	DirPerm os.FileMode
+	// MinBackoff is the interval before the first retry after a failure.
+	// It doubles after each failed retry until it reaches MaxBackoff.
+	// If MinBackoff is not specified, (100 * time.Millisecond) is used.
+	MinBackoff time.Duration
+	// MaxBackoff is the max interval between retries.
+	// If MaxBackoff is not specified, (30 * time.Second) is used.
+	MaxBackoff time.Duration
+	// SegmentSize is the size of a segment file of the spool. When a segment
+	// file reaches it, a new one will be created.
+	// If SegmentSize is not specified, (4 * 1024 * 1024) is used.
+	SegmentSize int64
+	// MaxSize is the max total size of all segment files of the spool. When it
+	// is reached, new logs will be dropped and reported to the ErrorHandler.
+	// If MaxSize is not specified, (256 * 1024 * 1024) is used.
+	MaxSize int64
+	// ErrorHandler will be called when a log is dropped or a corrupted segment
+	// is skipped if it is not nil. The record passed to it may be nil when
+	// the error is NOT related to a specific log.
+	ErrorHandler writer.ErrorHandler
+}
+
+func (config *Config) setDefaults() {
+	if config.Dir == "" {
+		config.Dir = filepath.Join(os.TempDir(), "gxlog", "spool",
+			filepath.Base(os.Args[0]))
+	}
+	if config.DirPerm == 0 {
+		config.DirPerm = 0700
+	}
+	if config.MinBackoff == 0 {
+		config.MinBackoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+	if config.SegmentSize == 0 {
+		config.SegmentSize = 4 * 1024 * 1024
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = 256 * 1024 * 1024
+	}
+}
+
+func (config *Config) check() error {
+	if config.MinBackoff < 0 {
+		return errors.New("Config.MinBackoff must NOT be negative")
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		return errors.New("Config.MaxBackoff must NOT be less than Config.MinBackoff")
+	}
+	if config.SegmentSize < 0 {
+		return errors.New("Config.SegmentSize must NOT be negative")
+	}
+	if config.MaxSize < config.SegmentSize {
+		return errors.New("Config.MaxSize must NOT be less than Config.SegmentSize")
+	}
+	return nil
+}
@@ -0,0 +1,382 @@
+package retry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	segmentExt = ".seg"
+	cursorName = "cursor"
+	headerSize = 8 // 4 bytes of length and 4 bytes of crc32 checksum
+)
+
+var (
+	errSpoolFull = errors.New("spool is full")
+	errTruncated = errors.New("truncated entry")
+	errLength    = errors.New("invalid entry length")
+	errChecksum  = errors.New("checksum mismatch")
+)
+
+// A skipError reports that a corrupted segment file has been skipped. Unlike
+// other errors of a spool, the spool has made progress.
+type skipError struct {
+	seq   int64
+	cause error
+}
+
+func (err *skipError) Error() string {
+	return fmt.Sprintf("skip corrupted spool segment %d: %v", err.seq, err.cause)
+}
+
+// A spool is a bounded on-disk FIFO queue which consists of segment files.
+// Each entry in a segment file is prefixed with its length and its checksum.
+// The read position is persisted in the cursor file, such it survives
+// process restarts.
+type spool struct {
+	dir         string
+	segmentSize int64
+	maxSize     int64
+
+	segments []int64 // sequence numbers of segment files in ascending order
+	size     int64   // total size of all segment files
+
+	wfile *os.File // the last segment file, for appending
+	wsize int64
+
+	rfile   *os.File // the first segment file, for reading
+	roffset int64
+	rlength int64 // length of the entry got by the last peek
+}
+
+func openSpool(dir string, perm os.FileMode, segmentSize, maxSize int64) (*spool, error) {
+	if err := os.MkdirAll(dir, perm); err != nil {
+		return nil, err
+	}
+	sp := &spool{
+		dir:         dir,
+		segmentSize: segmentSize,
+		maxSize:     maxSize,
+	}
+	if err := sp.load(); err != nil {
+		sp.Close()
+		return nil, err
+	}
+	return sp, nil
+}
+
+// Push appends the payload as a new entry to the spool.
+func (sp *spool) Push(payload []byte) error {
+	entrySize := int64(headerSize + len(payload))
+	if sp.size+entrySize > sp.maxSize {
+		return errSpoolFull
+	}
+	if sp.wfile == nil || sp.wsize >= sp.segmentSize {
+		if err := sp.createSegment(); err != nil {
+			return err
+		}
+	}
+	entry := make([]byte, headerSize, entrySize)
+	binary.BigEndian.PutUint32(entry, uint32(len(payload)))
+	binary.BigEndian.PutUint32(entry[4:], crc32.ChecksumIEEE(payload))
+	entry = append(entry, payload...)
+	n, err := sp.wfile.Write(entry)
+	sp.wsize += int64(n)
+	sp.size += int64(n)
+	return err
+}
+
+// Peek returns the payload of the first entry without removing it.
+// It returns nil if the spool is empty. If the first segment file is
+// corrupted, the rest of it is skipped and a *skipError is returned.
+func (sp *spool) Peek() ([]byte, error) {
+	for len(sp.segments) > 0 {
+		if sp.rfile == nil {
+			file, err := os.Open(sp.segmentPath(sp.segments[0]))
+			if err != nil {
+				return nil, sp.skipSegment(err)
+			}
+			sp.rfile = file
+		}
+		payload, err := sp.readEntry()
+		if err == io.EOF {
+			if len(sp.segments) == 1 {
+				return nil, nil
+			}
+			if err := sp.removeFirstSegment(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, sp.skipSegment(err)
+		}
+		sp.rlength = int64(headerSize + len(payload))
+		return payload, nil
+	}
+	return nil, nil
+}
+
+// Commit removes the entry returned by the last Peek.
+func (sp *spool) Commit() error {
+	sp.roffset += sp.rlength
+	sp.rlength = 0
+	return sp.saveCursor()
+}
+
+// Pending returns the size of entries that have not been committed.
+func (sp *spool) Pending() int64 {
+	return sp.size - sp.roffset
+}
+
+// Reset removes all segment files and the cursor file.
+func (sp *spool) Reset() error {
+	if err := sp.Close(); err != nil {
+		return err
+	}
+	for len(sp.segments) > 0 {
+		if err := sp.removeFirstSegment(); err != nil {
+			return err
+		}
+	}
+	err := os.Remove(filepath.Join(sp.dir, cursorName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (sp *spool) Close() error {
+	var err error
+	if sp.rfile != nil {
+		err = sp.rfile.Close()
+		sp.rfile = nil
+	}
+	if sp.wfile != nil {
+		if werr := sp.wfile.Close(); err == nil {
+			err = werr
+		}
+		sp.wfile = nil
+	}
+	return err
+}
+
+func (sp *spool) load() error {
+	infos, err := ioutil.ReadDir(sp.dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		sp.segments = append(sp.segments, seq)
+		sp.size += info.Size()
+	}
+	sort.Slice(sp.segments, func(i, j int) bool {
+		return sp.segments[i] < sp.segments[j]
+	})
+	if err := sp.loadCursor(); err != nil {
+		return err
+	}
+	if n := len(sp.segments); n > 0 {
+		return sp.openLastSegment()
+	}
+	return nil
+}
+
+// openLastSegment opens the last segment file for appending. An entry torn by
+// a crash at its end is truncated, so that new entries are NOT appended after
+// the garbage.
+func (sp *spool) openLastSegment() error {
+	last := sp.segments[len(sp.segments)-1]
+	file, err := os.OpenFile(sp.segmentPath(last), os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	size, err := validSize(file, sp.maxSize)
+	if err == nil && size < info.Size() {
+		err = file.Truncate(size)
+	}
+	if err != nil {
+		file.Close()
+		return err
+	}
+	sp.wfile = file
+	sp.wsize = size
+	sp.size -= info.Size() - size
+	return nil
+}
+
+func (sp *spool) loadCursor() error {
+	data, err := ioutil.ReadFile(filepath.Join(sp.dir, cursorName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var seq, offset int64
+	if _, err := fmt.Sscanf(string(data), "%d %d", &seq, &offset); err != nil {
+		// a corrupted cursor makes all spooled logs be replayed again
+		return nil
+	}
+	for len(sp.segments) > 0 && sp.segments[0] < seq {
+		if err := sp.removeFirstSegment(); err != nil {
+			return err
+		}
+	}
+	if len(sp.segments) > 0 && sp.segments[0] == seq {
+		sp.roffset = offset
+	}
+	return nil
+}
+
+func (sp *spool) saveCursor() error {
+	if len(sp.segments) == 0 {
+		return nil
+	}
+	pathname := filepath.Join(sp.dir, cursorName)
+	data := fmt.Sprintf("%d %d\n", sp.segments[0], sp.roffset)
+	if err := ioutil.WriteFile(pathname+".tmp", []byte(data), 0600); err != nil {
+		return err
+	}
+	return os.Rename(pathname+".tmp", pathname)
+}
+
+func (sp *spool) readEntry() ([]byte, error) {
+	return readEntryAt(sp.rfile, sp.roffset, sp.maxSize)
+}
+
+// skipSegment drops the first segment file after it fails to be read.
+func (sp *spool) skipSegment(cause error) error {
+	seq := sp.segments[0]
+	if len(sp.segments) == 1 {
+		// make sure new entries will not be appended to a dropped segment file
+		if err := sp.createSegment(); err != nil {
+			return err
+		}
+	}
+	if err := sp.removeFirstSegment(); err != nil {
+		return err
+	}
+	return &skipError{seq: seq, cause: cause}
+}
+
+func (sp *spool) createSegment() error {
+	var seq int64
+	if n := len(sp.segments); n > 0 {
+		seq = sp.segments[n-1] + 1
+	}
+	file, err := os.OpenFile(sp.segmentPath(seq),
+		os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if sp.wfile != nil {
+		sp.wfile.Close()
+	}
+	sp.wfile = file
+	sp.wsize = 0
+	sp.segments = append(sp.segments, seq)
+	return nil
+}
+
+func (sp *spool) removeFirstSegment() error {
+	seq := sp.segments[0]
+	pathname := sp.segmentPath(seq)
+	if sp.rfile != nil {
+		sp.rfile.Close()
+		sp.rfile = nil
+	}
+	if len(sp.segments) == 1 && sp.wfile != nil {
+		sp.wfile.Close()
+		sp.wfile = nil
+	}
+	var size int64
+	if info, err := os.Stat(pathname); err == nil {
+		size = info.Size()
+	}
+	if err := os.Remove(pathname); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	sp.segments = sp.segments[1:]
+	sp.size -= size
+	sp.roffset = 0
+	sp.rlength = 0
+	return sp.saveCursor()
+}
+
+func (sp *spool) segmentPath(seq int64) string {
+	return filepath.Join(sp.dir, fmt.Sprintf("%016d%s", seq, segmentExt))
+}
+
+// readEntryAt reads the payload of the entry at the offset of the file. It
+// returns io.EOF if there is no entry at the offset, or one of errTruncated,
+// errLength and errChecksum if the entry is corrupted.
+func readEntryAt(file *os.File, offset, maxSize int64) ([]byte, error) {
+	var header [headerSize]byte
+	n, err := file.ReadAt(header[:], offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, io.EOF
+	}
+	if n < headerSize {
+		return nil, errTruncated
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	checksum := binary.BigEndian.Uint32(header[4:])
+	if int64(length) > maxSize {
+		return nil, errLength
+	}
+	payload := make([]byte, length)
+	n, err = file.ReadAt(payload, offset+headerSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n < len(payload) {
+		return nil, errTruncated
+	}
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, errChecksum
+	}
+	return payload, nil
+}
+
+// validSize returns the size of the leading entries of the file that are NOT
+// corrupted.
+func validSize(file *os.File, maxSize int64) (int64, error) {
+	var offset int64
+	for {
+		payload, err := readEntryAt(file, offset, maxSize)
+		switch err {
+		case nil:
+			offset += int64(headerSize + len(payload))
+		case io.EOF, errTruncated, errLength, errChecksum:
+			return offset, nil
+		default:
+			return 0, err
+		}
+	}
+}
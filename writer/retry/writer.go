@@ -0,0 +1,233 @@
+// Package retry implements a writer wrapper which retries with exponential
+// backoff when the underlying writer fails.
+//
+// While the underlying writer is failing, logs are spooled to a bounded on-disk
+// queue and replayed in order once the underlying writer recovers. Spooled logs
+// survive process restarts, they will be replayed when a retry writer is opened
+// with the same directory again. It is useful for writers of remote backends
+// that must NOT lose logs during a network blip, e.g. audit logs.
+package retry
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer"
+)
+
+type entry struct {
+	Bytes  []byte
+	Record iface.Record
+}
+
+// A Writer implements the interface iface.Writer.
+//
+// All methods of a Writer are concurrency safe.
+// A Writer MUST be created with Open.
+type Writer struct {
+	sender writer.Sender
+	config Config
+
+	spool     *spool
+	replaying bool
+	closed    bool
+	chanClose chan struct{}
+	wg        sync.WaitGroup
+
+	lock sync.Mutex
+}
+
+// Open creates a new Writer that wraps the sender with the config. The sender
+// must NOT be nil. If there are logs left in the spool, they will be replayed
+// before any new log.
+func Open(sender writer.Sender, config Config) (*Writer, error) {
+	config.setDefaults()
+	if err := config.check(); err != nil {
+		return nil, fmt.Errorf("writer/retry.Open: %v", err)
+	}
+	sp, err := openSpool(config.Dir, config.DirPerm, config.SegmentSize,
+		config.MaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("writer/retry.Open: %v", err)
+	}
+	wt := &Writer{
+		sender:    sender,
+		config:    config,
+		spool:     sp,
+		chanClose: make(chan struct{}),
+	}
+	if sp.Pending() > 0 {
+		wt.startReplaying()
+	}
+	return wt, nil
+}
+
+// Close stops replaying and closes the spool. Logs that have not been replayed
+// are left in the spool. It does NOT close the underlying writer.
+// Calling Close more than once is a no-op.
+func (writer *Writer) Close() error {
+	writer.lock.Lock()
+	if writer.closed {
+		writer.lock.Unlock()
+		return nil
+	}
+	writer.closed = true
+	writer.lock.Unlock()
+
+	close(writer.chanClose)
+	writer.wg.Wait()
+
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if err := writer.spool.Close(); err != nil {
+		return fmt.Errorf("writer/retry.Close: %v", err)
+	}
+	return nil
+}
+
+// Write implements the interface Writer. It sends the bs and record to the
+// underlying writer. If the underlying writer fails or there are logs in the
+// spool, it appends the bs and record to the spool instead and another
+// goroutine will replay them later.
+func (writer *Writer) Write(bs []byte, record *iface.Record) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if !writer.replaying {
+		if writer.sender.Send(bs, record) == nil {
+			return
+		}
+		writer.startReplaying()
+	}
+	payload, err := json.Marshal(entry{Bytes: bs, Record: *record})
+	if err == nil {
+		err = writer.spool.Push(payload)
+	}
+	if err != nil && writer.config.ErrorHandler != nil {
+		writer.config.ErrorHandler(bs, record, err)
+	}
+}
+
+// Pending returns the size in bytes of logs in the spool that have not been
+// replayed.
+func (writer *Writer) Pending() int64 {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.spool.Pending()
+}
+
+func (writer *Writer) startReplaying() {
+	writer.replaying = true
+	writer.wg.Add(1)
+	go writer.replay()
+}
+
+func (writer *Writer) replay() {
+	defer writer.wg.Done()
+
+	backoff := writer.config.MinBackoff
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-writer.chanClose:
+			return
+		}
+		done, err := writer.replayAll()
+		if done {
+			return
+		}
+		if err == nil {
+			backoff = writer.config.MinBackoff
+		} else {
+			backoff *= 2
+			if backoff > writer.config.MaxBackoff {
+				backoff = writer.config.MaxBackoff
+			}
+		}
+		timer.Reset(backoff)
+	}
+}
+
+// replayAll sends spooled logs to the underlying writer until the spool is
+// empty, the underlying writer fails or the Writer is closed.
+func (writer *Writer) replayAll() (done bool, err error) {
+	for {
+		select {
+		case <-writer.chanClose:
+			return true, nil
+		default:
+		}
+		data, ok, err := writer.peek()
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return true, nil
+		}
+		if data == nil {
+			continue
+		}
+		if err := writer.sender.Send(data.Bytes, &data.Record); err != nil {
+			return false, err
+		}
+		writer.commit(data)
+	}
+}
+
+// peek returns the first log in the spool. If the spool is empty, it stops
+// replaying and returns false. A nil log with a nil error means the first
+// entry has been dropped and peek should be called again. A non-nil error
+// means the spool fails and replaying should back off.
+func (writer *Writer) peek() (*entry, bool, error) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	payload, err := writer.spool.Peek()
+	if err != nil {
+		writer.handleError(nil, nil, err)
+		if _, ok := err.(*skipError); ok {
+			return nil, true, nil
+		}
+		return nil, true, err
+	}
+	if payload == nil {
+		if err := writer.spool.Reset(); err != nil {
+			writer.handleError(nil, nil, err)
+		}
+		writer.replaying = false
+		return nil, false, nil
+	}
+	var data entry
+	if err := json.Unmarshal(payload, &data); err != nil {
+		writer.handleError(payload, nil, err)
+		writer.commitLocked(payload, nil)
+		return nil, true, nil
+	}
+	return &data, true, nil
+}
+
+func (writer *Writer) commit(data *entry) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.commitLocked(data.Bytes, &data.Record)
+}
+
+func (writer *Writer) commitLocked(bs []byte, record *iface.Record) {
+	if err := writer.spool.Commit(); err != nil {
+		writer.handleError(bs, record, err)
+	}
+}
+
+func (writer *Writer) handleError(bs []byte, record *iface.Record, err error) {
+	if writer.config.ErrorHandler != nil {
+		writer.config.ErrorHandler(bs, record, err)
+	}
+}
@@ -0,0 +1,365 @@
+package retry_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/retry"
+)
+
+type flakySender struct {
+	down     bool
+	attempts int
+	logs     []string
+	lock     sync.Mutex
+}
+
+func (sender *flakySender) Write(bs []byte, record *iface.Record) {
+	sender.Send(bs, record)
+}
+
+func (sender *flakySender) Send(bs []byte, record *iface.Record) error {
+	sender.lock.Lock()
+	defer sender.lock.Unlock()
+
+	sender.attempts++
+	if sender.down {
+		return errors.New("sink is down")
+	}
+	sender.logs = append(sender.logs, string(bs))
+	return nil
+}
+
+func (sender *flakySender) SetDown(down bool) {
+	sender.lock.Lock()
+	defer sender.lock.Unlock()
+
+	sender.down = down
+}
+
+func (sender *flakySender) Attempts() int {
+	sender.lock.Lock()
+	defer sender.lock.Unlock()
+
+	return sender.attempts
+}
+
+func (sender *flakySender) Logs() []string {
+	sender.lock.Lock()
+	defer sender.lock.Unlock()
+
+	return append([]string(nil), sender.logs...)
+}
+
+func TestReplayInOrder(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	sender := &flakySender{}
+	wt := openWriter(t, sender, dir)
+	defer wt.Close()
+
+	write(wt, 0, 3)
+	sender.SetDown(true)
+	write(wt, 3, 6)
+	sender.SetDown(false)
+	write(wt, 6, 9)
+
+	waitDrained(t, wt)
+	checkLogs(t, sender.Logs(), 0, 9)
+}
+
+func TestReplayAfterRestart(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	sender := &flakySender{down: true}
+	wt := openWriter(t, sender, dir)
+	write(wt, 0, 5)
+	if err := wt.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sender.SetDown(false)
+	wt = openWriter(t, sender, dir)
+	defer wt.Close()
+	write(wt, 5, 7)
+
+	waitDrained(t, wt)
+	checkLogs(t, sender.Logs(), 0, 7)
+}
+
+type errorCollector struct {
+	errs []error
+	lock sync.Mutex
+}
+
+func (collector *errorCollector) Handle(bs []byte, record *iface.Record, err error) {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+
+	collector.errs = append(collector.errs, err)
+}
+
+func (collector *errorCollector) Errors() []error {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+
+	return append([]error(nil), collector.errs...)
+}
+
+func TestRetryFailedSend(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	sender := &flakySender{down: true}
+	wt := openWriter(t, sender, dir)
+	defer wt.Close()
+
+	write(wt, 0, 3)
+	deadline := time.Now().Add(5 * time.Second)
+	for sender.Attempts() < 6 {
+		if time.Now().After(deadline) {
+			t.Fatal("TestRetryFailedSend: failed sends are not retried")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if logs := sender.Logs(); len(logs) != 0 {
+		t.Fatalf("TestRetryFailedSend: unexpected logs %q", logs)
+	}
+	sender.SetDown(false)
+
+	waitDrained(t, wt)
+	checkLogs(t, sender.Logs(), 0, 3)
+}
+
+func TestCorruptedSegment(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	sender := &flakySender{down: true}
+	wt := openWriter(t, sender, dir)
+	write(wt, 0, 5)
+	if err := wt.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// each entry is larger than the segment size, so the first segment holds
+	// only the first log
+	pathname := filepath.Join(dir, "0000000000000000.seg")
+	data, err := ioutil.ReadFile(pathname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := ioutil.WriteFile(pathname, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sender.SetDown(false)
+	collector := &errorCollector{}
+	wt = openWriter(t, sender, dir, func(config *retry.Config) {
+		config.ErrorHandler = collector.Handle
+	})
+	defer wt.Close()
+
+	waitDrained(t, wt)
+	checkLogs(t, sender.Logs(), 1, 5)
+	errs := collector.Errors()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "checksum mismatch") {
+		t.Errorf("TestCorruptedSegment: unexpected errors %v", errs)
+	}
+}
+
+func TestPersistentSpoolError(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	sender := &flakySender{down: true}
+	collector := &errorCollector{}
+	wt := openWriter(t, sender, dir, func(config *retry.Config) {
+		config.ErrorHandler = collector.Handle
+	})
+	defer wt.Close()
+
+	write(wt, 0, 5)
+	deadline := time.Now().Add(5 * time.Second)
+	for sender.Attempts() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("TestPersistentSpoolError: failed sends are not retried")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// a non-empty directory in place of the first segment file fails to be
+	// removed, even by root
+	pathname := filepath.Join(dir, "0000000000000000.seg")
+	data, err := ioutil.ReadFile(pathname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(pathname); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(pathname, "blocker"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	sender.SetDown(false)
+	time.Sleep(200 * time.Millisecond)
+	if n := len(collector.Errors()); n == 0 || n > 100 {
+		t.Fatalf("TestPersistentSpoolError: got %d errors, replaying does NOT back off", n)
+	}
+	if err := os.RemoveAll(pathname); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(pathname, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	waitDrained(t, wt)
+	checkLogs(t, sender.Logs(), 0, 5)
+}
+
+func TestTornSegmentTail(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	largeSegment := func(config *retry.Config) {
+		config.SegmentSize = 4096
+	}
+	sender := &flakySender{down: true}
+	wt := openWriter(t, sender, dir, largeSegment)
+	write(wt, 0, 3)
+	if err := wt.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// simulate a crash in the middle of appending an entry
+	pathname := filepath.Join(dir, "0000000000000000.seg")
+	file, err := os.OpenFile(pathname, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var torn [11]byte
+	binary.BigEndian.PutUint32(torn[:], 100)
+	_, err = file.Write(torn[:])
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector := &errorCollector{}
+	wt = openWriter(t, sender, dir, largeSegment, func(config *retry.Config) {
+		config.ErrorHandler = collector.Handle
+	})
+	defer wt.Close()
+	write(wt, 3, 6)
+	sender.SetDown(false)
+
+	waitDrained(t, wt)
+	checkLogs(t, sender.Logs(), 0, 6)
+	if errs := collector.Errors(); len(errs) != 0 {
+		t.Errorf("TestTornSegmentTail: unexpected errors %v", errs)
+	}
+}
+
+func TestSpoolFull(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	sender := &flakySender{down: true}
+	collector := &errorCollector{}
+	wt := openWriter(t, sender, dir, func(config *retry.Config) {
+		config.MaxSize = 1024
+		config.ErrorHandler = collector.Handle
+	})
+	defer wt.Close()
+
+	write(wt, 0, 20)
+	errs := collector.Errors()
+	if len(errs) == 0 {
+		t.Fatal("TestSpoolFull: no log is dropped")
+	}
+	for _, err := range errs {
+		if !strings.Contains(err.Error(), "spool is full") {
+			t.Fatalf("TestSpoolFull: unexpected error %v", err)
+		}
+	}
+	sender.SetDown(false)
+
+	waitDrained(t, wt)
+	checkLogs(t, sender.Logs(), 0, 20-len(errs))
+}
+
+func TestCloseTwice(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	wt := openWriter(t, &flakySender{}, dir)
+	if err := wt.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func openWriter(t *testing.T, sender *flakySender, dir string,
+	opts ...func(*retry.Config)) *retry.Writer {
+
+	config := retry.Config{
+		Dir:         dir,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  10 * time.Millisecond,
+		SegmentSize: 64,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	wt, err := retry.Open(sender, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wt
+}
+
+func write(wt *retry.Writer, begin, end int) {
+	for i := begin; i < end; i++ {
+		wt.Write([]byte(strconv.Itoa(i)), &iface.Record{Level: iface.Info})
+	}
+}
+
+func waitDrained(t *testing.T, wt *retry.Writer) {
+	deadline := time.Now().Add(5 * time.Second)
+	for wt.Pending() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("spool is not drained")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func checkLogs(t *testing.T, logs []string, begin, end int) {
+	if len(logs) != end-begin {
+		t.Fatalf("checkLogs:\noutput: %q\nexpect: %d logs", logs, end-begin)
+	}
+	for i, log := range logs {
+		if log != strconv.Itoa(begin+i) {
+			t.Fatalf("checkLogs:\noutput: %q\nexpect: in order from %d", logs, begin)
+		}
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gxlog-retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
@@ -0,0 +1,147 @@
+package writer
+
+import (
+	"sync"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+// The Predicate type defines a function type which is used to decide whether
+// a log is routed to a Writer.
+//
+// Do NOT call any method of the Logger within a predicate, or it may deadlock.
+type Predicate func(record *iface.Record) bool
+
+// LevelIs returns a Predicate which matches logs of the level.
+func LevelIs(level iface.Level) Predicate {
+	return func(record *iface.Record) bool {
+		return record.Level == level
+	}
+}
+
+// LevelAtLeast returns a Predicate which matches logs whose level is NOT lower
+// than the level.
+func LevelAtLeast(level iface.Level) Predicate {
+	return func(record *iface.Record) bool {
+		return record.Level >= level
+	}
+}
+
+// HasContext returns a Predicate which matches logs that have a context with
+// the key.
+func HasContext(key string) Predicate {
+	return func(record *iface.Record) bool {
+		for _, context := range record.Aux.Contexts {
+			if context.Key == key {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ContextIs returns a Predicate which matches logs that have a context with
+// the key and the value.
+func ContextIs(key, value string) Predicate {
+	return func(record *iface.Record) bool {
+		for _, context := range record.Aux.Contexts {
+			if context.Key == key && context.Value == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type routeRule struct {
+	predicate Predicate
+	writer    iface.Writer
+}
+
+// A Route is a Writer wrapper that dispatches logs to different Writers.
+// A log is written to the Writer of each rule it matches in the order the rules
+// are added. If it matches no rule, it is written to the fallback Writer.
+//
+// All methods of a Route are concurrency safe.
+// A Route MUST be created with NewRoute.
+type Route struct {
+	rules    []routeRule
+	fallback iface.Writer
+
+	lock sync.Mutex
+}
+
+// NewRoute creates a new Route with the fallback Writer. The fallback may be
+// nil, and then logs that match no rule are ignored.
+func NewRoute(fallback iface.Writer) *Route {
+	return &Route{fallback: fallback}
+}
+
+// Add adds a rule that routes logs matching the predicate to the writer.
+// The predicate and the writer must NOT be nil.
+func (route *Route) Add(predicate Predicate, writer iface.Writer) *Route {
+	route.lock.Lock()
+	defer route.lock.Unlock()
+
+	// copy on write, such Write can iterate rules without holding the lock
+	rules := make([]routeRule, len(route.rules), len(route.rules)+1)
+	copy(rules, route.rules)
+	route.rules = append(rules, routeRule{predicate: predicate, writer: writer})
+	return route
+}
+
+// AddLevel adds a rule that routes logs whose level is NOT lower than the level
+// to the writer. The writer must NOT be nil.
+func (route *Route) AddLevel(level iface.Level, writer iface.Writer) *Route {
+	return route.Add(LevelAtLeast(level), writer)
+}
+
+// AddContext adds a rule that routes logs which have a context with the key
+// to the writer. The writer must NOT be nil.
+func (route *Route) AddContext(key string, writer iface.Writer) *Route {
+	return route.Add(HasContext(key), writer)
+}
+
+// Reset removes all rules of the Route.
+func (route *Route) Reset() {
+	route.lock.Lock()
+	defer route.lock.Unlock()
+
+	route.rules = nil
+}
+
+// Fallback returns the fallback Writer of the Route.
+func (route *Route) Fallback() iface.Writer {
+	route.lock.Lock()
+	defer route.lock.Unlock()
+
+	return route.fallback
+}
+
+// SetFallback sets the fallback Writer of the Route. It may be nil.
+func (route *Route) SetFallback(writer iface.Writer) {
+	route.lock.Lock()
+	defer route.lock.Unlock()
+
+	route.fallback = writer
+}
+
+// Write implements the interface Writer. It writes the bs and record to the
+// Writer of each rule the record matches, or to the fallback Writer if the
+// record matches no rule.
+func (route *Route) Write(bs []byte, record *iface.Record) {
+	route.lock.Lock()
+	rules, fallback := route.rules, route.fallback
+	route.lock.Unlock()
+
+	matched := false
+	for _, rule := range rules {
+		if rule.predicate(record) {
+			rule.writer.Write(bs, record)
+			matched = true
+		}
+	}
+	if !matched && fallback != nil {
+		fallback.Write(bs, record)
+	}
+}
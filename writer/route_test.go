@@ -0,0 +1,71 @@
+package writer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer"
+)
+
+type recorder struct {
+	logs []string
+}
+
+func (rec *recorder) Write(bs []byte, record *iface.Record) {
+	rec.logs = append(rec.logs, string(bs))
+}
+
+func TestRouteByLevel(t *testing.T) {
+	errors, warns, fallback := &recorder{}, &recorder{}, &recorder{}
+	route := writer.NewRoute(fallback).
+		AddLevel(iface.Error, errors).
+		Add(writer.LevelIs(iface.Warn), warns)
+
+	write(route, iface.Info, "info")
+	write(route, iface.Warn, "warn")
+	write(route, iface.Error, "error")
+	write(route, iface.Fatal, "fatal")
+	write(route, iface.Debug, "debug")
+
+	checkLogs(t, errors, "error", "fatal")
+	checkLogs(t, warns, "warn")
+	checkLogs(t, fallback, "info", "debug")
+}
+
+func TestRouteByContext(t *testing.T) {
+	audit, both := &recorder{}, &recorder{}
+	route := writer.NewRoute(nil).
+		AddContext("audit", audit).
+		Add(writer.ContextIs("user", "root"), both).
+		AddLevel(iface.Error, both)
+
+	route.Write([]byte("audit"), &iface.Record{
+		Level: iface.Info,
+		Aux: iface.Auxiliary{Contexts: []iface.Context{
+			{Key: "audit", Value: "1"},
+			{Key: "user", Value: "root"},
+		}},
+	})
+	write(route, iface.Info, "ignored")
+	write(route, iface.Error, "error")
+
+	checkLogs(t, audit, "audit")
+	checkLogs(t, both, "audit", "error")
+
+	route.Reset()
+	route.SetFallback(audit)
+	write(route, iface.Error, "fallback")
+	checkLogs(t, audit, "audit", "fallback")
+	checkLogs(t, both, "audit", "error")
+}
+
+func write(wt iface.Writer, level iface.Level, log string) {
+	wt.Write([]byte(log), &iface.Record{Level: level})
+}
+
+func checkLogs(t *testing.T, rec *recorder, expect ...string) {
+	if !reflect.DeepEqual(rec.logs, expect) {
+		t.Errorf("checkLogs:\noutput: %q\nexpect: %q", rec.logs, expect)
+	}
+}
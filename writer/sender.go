@@ -0,0 +1,16 @@
+package writer
+
+import (
+	"github.com/fufuok/gxlog/iface"
+)
+
+// A Sender is a Writer that reports whether a log has been written successfully.
+// Writers of remote backends implement it so that wrappers, such as the retry
+// writer, can react to failures rather than only having them reported to an
+// ErrorHandler.
+type Sender interface {
+	iface.Writer
+	// Send writes the bs and record and returns the error if any. Unlike Write,
+	// Send must NOT call any ErrorHandler.
+	Send(bs []byte, record *iface.Record) error
+}
@@ -0,0 +1,51 @@
+package socket
+
+import (
+	"github.com/fufuok/gxlog/iface"
+)
+
+type backlogEntry struct {
+	Bytes  []byte
+	Record *iface.Record
+}
+
+// A backlog keeps the last logs in memory, bounded by the count and/or the
+// total size of them. A bound that is 0 means no bound.
+type backlog struct {
+	maxLogs  int
+	maxBytes int
+	entries  []backlogEntry
+	size     int
+}
+
+func newBacklog(maxLogs, maxBytes int) *backlog {
+	if maxLogs <= 0 && maxBytes <= 0 {
+		return nil
+	}
+	return &backlog{maxLogs: maxLogs, maxBytes: maxBytes}
+}
+
+func (blog *backlog) Push(bs []byte, record *iface.Record) {
+	blog.entries = append(blog.entries, backlogEntry{Bytes: bs, Record: record})
+	blog.size += len(bs)
+	for len(blog.entries) > 0 &&
+		(blog.maxLogs > 0 && len(blog.entries) > blog.maxLogs ||
+			blog.maxBytes > 0 && blog.size > blog.maxBytes) {
+		blog.size -= len(blog.entries[0].Bytes)
+		// zero the evicted entry to release its memory
+		blog.entries[0] = backlogEntry{}
+		blog.entries = blog.entries[1:]
+	}
+}
+
+// Select returns the logs in the backlog that the client matches, from the
+// oldest to the newest.
+func (blog *backlog) Select(clt *client) [][]byte {
+	var selected [][]byte
+	for _, entry := range blog.entries {
+		if clt.Match(entry.Record) {
+			selected = append(selected, entry.Bytes)
+		}
+	}
+	return selected
+}
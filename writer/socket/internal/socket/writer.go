@@ -1,30 +1,166 @@
+// Package socket implements the socket writer shared by the tcp and unix socket
+// writers.
+//
+// If the backlog is enabled in the options, the last logs are kept in memory and
+// replayed to each new subscriber first, such the context that led to a problem
+// is NOT lost.
+//
+// A subscriber may send a line of filter terms separated by spaces at any time
+// to receive only the logs that match all the terms, e.g.
+//
+//	level>=warn pkg=github.com/foo/db ctx.reqid=abc
+//
+// Each line replaces the previous filter and an empty line removes it. The
+// first line also filters the replayed backlog if it is sent in time.
+// A subscriber that sends nothing receives all logs. Supported terms are:
+//
+//	level<op><level>  op is one of >=, <=, >, <, = and !=, level is one of
+//	                  trace, debug, info, warn, error and fatal or their
+//	                  first letter, e.g. level>=warn
+//	<field><op><str>  field is one of file, pkg, func, prefix and msg,
+//	                  op is one of = and != that match the str exactly or
+//	                  the prefix of the str if it ends with '*', or ~ that
+//	                  matches a substring, e.g. pkg=github.com/foo/*
+//	ctx.<key>         matches logs that have a context with the key
+//	ctx.<key><op><str>  matches the value of the context with the key in
+//	                  the same manner as <field><op><str>, e.g. ctx.reqid=abc
+//	marked[=<bool>]   matches marked or unmarked logs
 package socket
 
 import (
+	"bufio"
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/internal/filter"
 )
 
+// The SlowPolicy defines the type of policy for subscribers that can NOT keep
+// up with logs.
+type SlowPolicy int
+
+// All available policies here.
+const (
+	// Logs are dropped while the queue of a subscriber is full, and then a
+	// notice of how many logs have been dropped is sent to the subscriber.
+	DropWithNotice SlowPolicy = iota
+	// A subscriber is disconnected once its queue is full.
+	Disconnect
+)
+
+// Stats is the statistics of a socket writer.
+type Stats struct {
+	// Clients is the count of connected subscribers.
+	Clients int
+	// DroppedLogs is the total count of logs dropped for slow subscribers.
+	DroppedLogs int64
+	// DroppedBytes is the total size of logs dropped for slow subscribers.
+	DroppedBytes int64
+	// Disconnects is the total count of subscribers disconnected for being slow,
+	// stalled or beyond the max count of connections.
+	Disconnects int64
+}
+
+// Options are the options of a socket writer that are common to all kinds of
+// transport.
+type Options struct {
+	// MaxConns is the max count of subscribers. Subscribers beyond it will be
+	// disconnected right after they connect.
+	// If MaxConns is not specified, 64 is used.
+	MaxConns int
+	// QueueSize is the capacity of the queue of logs of each subscriber.
+	// Each subscriber has its own goroutine to write logs in its queue, such
+	// a slow subscriber will NOT block the Logger.
+	// If QueueSize is not specified, 1024 is used.
+	QueueSize int
+	// WriteTimeout is the timeout of writing a log to a subscriber. A subscriber
+	// that times out will be disconnected.
+	// If WriteTimeout is not specified, (5 * time.Second) is used.
+	WriteTimeout time.Duration
+	// SlowPolicy specifies what to do when the queue of a subscriber is full.
+	// If SlowPolicy is not specified, DropWithNotice is used.
+	SlowPolicy SlowPolicy
+	// BacklogLogs is the max count of the last logs kept in memory, which will
+	// be replayed to each new subscriber before any new log.
+	// If neither BacklogLogs nor BacklogBytes is specified, the backlog is
+	// disabled.
+	BacklogLogs int
+	// BacklogBytes is the max total size of the last logs kept in memory.
+	BacklogBytes int
+	// BacklogDelay is how long to wait for the filter line of a new subscriber
+	// before the backlog is replayed, such only the logs matching the filter
+	// will be replayed. If BacklogDelay is negative, the backlog is replayed
+	// right after a subscriber connects.
+	// If BacklogDelay is not specified, (100 * time.Millisecond) is used.
+	BacklogDelay time.Duration
+}
+
+func (opts *Options) setDefaults() {
+	if opts.MaxConns == 0 {
+		opts.MaxConns = 64
+	}
+	if opts.QueueSize == 0 {
+		opts.QueueSize = 1024
+	}
+	if opts.WriteTimeout == 0 {
+		opts.WriteTimeout = 5 * time.Second
+	}
+	if opts.BacklogDelay == 0 {
+		opts.BacklogDelay = 100 * time.Millisecond
+	}
+}
+
+type client struct {
+	// droppedLogs and droppedBytes are accessed atomically because they are
+	//   reset by the goroutine of the client without the lock held
+	conn         net.Conn
+	queue        chan []byte
+	droppedLogs  int64
+	droppedBytes int64
+	// filter holds a value of type filter.Filter, it is set by the goroutine reading
+	//   filter lines from the client
+	filter atomic.Value
+	// chanFilter is closed when the first filter line is received
+	chanFilter chan struct{}
+}
+
+func (clt *client) Match(record *iface.Record) bool {
+	fn, _ := clt.filter.Load().(filter.Filter)
+	return fn == nil || fn(record)
+}
+
 type Writer struct {
+	opts     Options
 	listener net.Listener
-	conns    map[int64]net.Conn
+	clients  map[int64]*client
+	pending  int // count of clients that are waiting for the backlog
+	backlog  *backlog
 	id       int64
 	wg       sync.WaitGroup
 
+	droppedLogs  int64
+	droppedBytes int64
+	disconnects  int64
+	closed       bool
+
 	lock sync.Mutex
 }
 
-func Open(network, addr string) (*Writer, error) {
+func Open(network, addr string, opts Options) (*Writer, error) {
+	opts.setDefaults()
 	listener, err := net.Listen(network, addr)
 	if err != nil {
 		return nil, fmt.Errorf("socket.Open: %v", err)
 	}
 	wt := &Writer{
+		opts:     opts,
 		listener: listener,
-		conns:    make(map[int64]net.Conn),
+		clients:  make(map[int64]*client),
+		backlog:  newBacklog(opts.BacklogLogs, opts.BacklogBytes),
 	}
 	wt.wg.Add(1)
 	go wt.serve()
@@ -36,32 +172,68 @@ func (writer *Writer) Close() error {
 		return fmt.Errorf("socket.Close: %v", err)
 	}
 
+	writer.lock.Lock()
+	writer.closed = true
+	for id := range writer.clients {
+		writer.removeClient(id)
+	}
+	writer.lock.Unlock()
+
 	writer.wg.Wait()
+	return nil
+}
 
+func (writer *Writer) Write(bs []byte, record *iface.Record) {
 	writer.lock.Lock()
 	defer writer.lock.Unlock()
 
-	for id, conn := range writer.conns {
-		conn.Close()
-		delete(writer.conns, id)
+	if writer.backlog != nil || len(writer.clients) > 0 {
+		// the bs may be released after Write returns
+		bs = append(make([]byte, 0, len(bs)), bs...)
+	}
+	if writer.backlog != nil {
+		writer.backlog.Push(bs, record)
+	}
+	for id, clt := range writer.clients {
+		if !clt.Match(record) {
+			continue
+		}
+		select {
+		case clt.queue <- bs:
+			continue
+		default:
+		}
+		writer.droppedLogs++
+		writer.droppedBytes += int64(len(bs))
+		if writer.opts.SlowPolicy == Disconnect {
+			writer.disconnects++
+			writer.removeClient(id)
+		} else {
+			atomic.AddInt64(&clt.droppedLogs, 1)
+			atomic.AddInt64(&clt.droppedBytes, int64(len(bs)))
+		}
 	}
+}
 
-	return nil
+func (writer *Writer) Addr() net.Addr {
+	return writer.listener.Addr()
 }
 
-func (writer *Writer) Write(bs []byte, record *iface.Record) {
+func (writer *Writer) Stats() Stats {
 	writer.lock.Lock()
 	defer writer.lock.Unlock()
 
-	for id, conn := range writer.conns {
-		if _, err := conn.Write(bs); err != nil {
-			conn.Close()
-			delete(writer.conns, id)
-		}
+	return Stats{
+		Clients:      len(writer.clients),
+		DroppedLogs:  writer.droppedLogs,
+		DroppedBytes: writer.droppedBytes,
+		Disconnects:  writer.disconnects,
 	}
 }
 
 func (writer *Writer) serve() {
+	defer writer.wg.Done()
+
 	for {
 		conn, err := writer.listener.Accept()
 		if err != nil {
@@ -70,11 +242,132 @@ func (writer *Writer) serve() {
 
 		writer.lock.Lock()
 
-		id := writer.id
-		writer.id++
-		writer.conns[id] = conn
+		if writer.closed {
+			writer.lock.Unlock()
+			conn.Close()
+			break
+		}
+		if len(writer.clients)+writer.pending >= writer.opts.MaxConns {
+			writer.disconnects++
+			writer.lock.Unlock()
+			conn.SetWriteDeadline(time.Now().Add(writer.opts.WriteTimeout))
+			conn.Write([]byte("gxlog: too many subscribers\n"))
+			conn.Close()
+			continue
+		}
+		writer.pending++
+		writer.wg.Add(1)
+		go writer.subscribe(conn)
+
+		writer.lock.Unlock()
+	}
+}
+
+// subscribe waits for the first filter line of a new client for a while if
+// the backlog is enabled, and then adds the client and serves it with the
+// matched logs in the backlog first.
+func (writer *Writer) subscribe(conn net.Conn) {
+	defer writer.wg.Done()
+
+	clt := &client{
+		conn:       conn,
+		queue:      make(chan []byte, writer.opts.QueueSize),
+		chanFilter: make(chan struct{}),
+	}
+	writer.wg.Add(1)
+	go writer.readFilters(clt)
+
+	if writer.backlog != nil && writer.opts.BacklogDelay > 0 {
+		timer := time.NewTimer(writer.opts.BacklogDelay)
+		select {
+		case <-clt.chanFilter:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
 
+	writer.lock.Lock()
+	writer.pending--
+	if writer.closed {
 		writer.lock.Unlock()
+		conn.Close()
+		return
+	}
+	var backlog [][]byte
+	if writer.backlog != nil {
+		backlog = writer.backlog.Select(clt)
+	}
+	id := writer.id
+	writer.id++
+	writer.clients[id] = clt
+	writer.lock.Unlock()
+
+	for _, bs := range backlog {
+		if err := writer.writeClient(clt, bs); err != nil {
+			writer.dropClient(id, clt)
+			return
+		}
+	}
+	for bs := range clt.queue {
+		if err := writer.writeClient(clt, bs); err != nil {
+			writer.dropClient(id, clt)
+			return
+		}
 	}
-	writer.wg.Done()
+}
+
+func (writer *Writer) dropClient(id int64, clt *client) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.clients[id] == clt {
+		writer.disconnects++
+		writer.removeClient(id)
+	}
+}
+
+// readFilters reads filter lines from the client until the connection is
+// closed. Each line replaces the current filter of the client and an empty
+// line removes it.
+func (writer *Writer) readFilters(clt *client) {
+	defer writer.wg.Done()
+
+	first := true
+	scanner := bufio.NewScanner(clt.conn)
+	for scanner.Scan() {
+		fn, err := filter.Parse(scanner.Text())
+		if err != nil {
+			clt.conn.SetWriteDeadline(time.Now().Add(writer.opts.WriteTimeout))
+			fmt.Fprintf(clt.conn, "gxlog: invalid filter: %v\n", err)
+		} else {
+			clt.filter.Store(fn)
+		}
+		if first {
+			close(clt.chanFilter)
+			first = false
+		}
+	}
+}
+
+func (writer *Writer) writeClient(clt *client, bs []byte) error {
+	deadline := time.Now().Add(writer.opts.WriteTimeout)
+	clt.conn.SetWriteDeadline(deadline)
+	if logs := atomic.SwapInt64(&clt.droppedLogs, 0); logs > 0 {
+		bytes := atomic.SwapInt64(&clt.droppedBytes, 0)
+		notice := fmt.Sprintf("gxlog: %d logs (%d bytes) dropped for the slow "+
+			"subscriber\n", logs, bytes)
+		if _, err := clt.conn.Write([]byte(notice)); err != nil {
+			return err
+		}
+	}
+	_, err := clt.conn.Write(bs)
+	return err
+}
+
+// removeClient MUST be called with the lock held.
+func (writer *Writer) removeClient(id int64) {
+	clt := writer.clients[id]
+	delete(writer.clients, id)
+	close(clt.queue)
+	clt.conn.Close()
 }
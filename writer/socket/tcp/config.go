@@ -1,9 +1,29 @@
 package tcp
 
+import (
+	"github.com/fufuok/gxlog/writer/socket/internal/socket"
+)
+
+// Options are the options common to socket writers.
+type Options = socket.Options
+
+// The SlowPolicy defines the type of policy for subscribers that can NOT keep
+// up with logs.
+type SlowPolicy = socket.SlowPolicy
+
+// All available policies here, see socket.SlowPolicy for details.
+const (
+	DropWithNotice = socket.DropWithNotice
+	Disconnect     = socket.Disconnect
+)
+
 // A Config is used to configure a tcp socket writer.
 type Config struct {
 	// If Tag is not specified, "localhost:9999" is used.
 	Addr string
+	// Options are the options common to socket writers, e.g. the queue size
+	// and the backlog of subscribers.
+	Options
 }
 
 func (config *Config) setDefaults() {
@@ -8,15 +8,24 @@
 // For performance and security, use a unix writer instead as long as the system
 // has support for unix domain socket. Otherwise, bind the address to localhost
 // only.
+//
+// Subscribers are served as described in the documentation of package
+// github.com/fufuok/gxlog/writer/socket/internal/socket: each one has its own
+// queue of logs, may receive a backlog of the last logs when it connects and
+// may send lines of filter terms to receive only the logs it is interested in.
 package tcp
 
 import (
 	"fmt"
+	"net"
 
 	"github.com/fufuok/gxlog/iface"
 	"github.com/fufuok/gxlog/writer/socket/internal/socket"
 )
 
+// Stats is the statistics of a Writer.
+type Stats = socket.Stats
+
 // A Writer implements the interface iface.Writer.
 //
 // All methods of a Writer are concurrency safe.
@@ -28,7 +37,7 @@ type Writer struct {
 // Open creates a new Writer with the config.
 func Open(config Config) (*Writer, error) {
 	config.setDefaults()
-	writer, err := socket.Open("tcp", config.Addr)
+	writer, err := socket.Open("tcp", config.Addr, config.Options)
 	if err != nil {
 		return nil, fmt.Errorf("writer/socket/tcp.Open: %v", err)
 	}
@@ -47,3 +56,13 @@ func (writer *Writer) Close() error {
 func (writer *Writer) Write(bs []byte, record *iface.Record) {
 	writer.writer.Write(bs, record)
 }
+
+// Addr returns the address that the Writer is listening on.
+func (writer *Writer) Addr() net.Addr {
+	return writer.writer.Addr()
+}
+
+// Stats returns the statistics of the Writer.
+func (writer *Writer) Stats() Stats {
+	return writer.writer.Stats()
+}
@@ -0,0 +1,199 @@
+package tcp_test
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/socket/tcp"
+)
+
+func TestSlowSubscriber(t *testing.T) {
+	wt := open(t, tcp.Config{Options: tcp.Options{QueueSize: 1}})
+	defer wt.Close()
+
+	slow := dial(t, wt)
+	defer slow.Close()
+	waitClients(t, wt, 1)
+
+	done := make(chan struct{})
+	go func() {
+		big := []byte(strings.Repeat("x", 1<<16) + "\n")
+		for i := 0; i < 256; i++ {
+			wt.Write(big, &iface.Record{Level: iface.Info})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the slow subscriber blocks Write")
+	}
+	if stats := wt.Stats(); stats.DroppedLogs == 0 || stats.DroppedBytes == 0 {
+		t.Errorf("TestSlowSubscriber: unexpected stats: %+v", stats)
+	}
+
+	chanNotice := make(chan bool, 1)
+	go func() {
+		reader := bufio.NewReaderSize(slow, 1<<17)
+		notice := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				chanNotice <- false
+				return
+			}
+			notice = notice || strings.Contains(line, "dropped")
+			if line == "last\n" {
+				chanNotice <- notice
+				return
+			}
+		}
+	}()
+	// "last" may be dropped as well until the queue is drained
+	for {
+		wt.Write([]byte("last\n"), &iface.Record{Level: iface.Info})
+		select {
+		case notice := <-chanNotice:
+			if !notice {
+				t.Error("TestSlowSubscriber: no notice of dropped logs")
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestMaxConns(t *testing.T) {
+	wt := open(t, tcp.Config{Options: tcp.Options{MaxConns: 1}})
+	defer wt.Close()
+
+	first := dial(t, wt)
+	defer first.Close()
+	waitClients(t, wt, 1)
+
+	second := dial(t, wt)
+	defer second.Close()
+	line, _ := bufio.NewReader(second).ReadString('\n')
+	if !strings.Contains(line, "too many") {
+		t.Errorf("TestMaxConns:\noutput: %q\nexpect: a rejection", line)
+	}
+	if stats := wt.Stats(); stats.Clients != 1 || stats.Disconnects != 1 {
+		t.Errorf("TestMaxConns: unexpected stats: %+v", stats)
+	}
+}
+
+func open(t *testing.T, config tcp.Config) *tcp.Writer {
+	config.Addr = "127.0.0.1:0"
+	wt, err := tcp.Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wt
+}
+
+func dial(t *testing.T, wt *tcp.Writer) net.Conn {
+	conn, err := net.Dial("tcp", wt.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func waitClients(t *testing.T, wt *tcp.Writer, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for wt.Stats().Clients != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("waitClients: expect %d clients", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	wt := open(t, tcp.Config{})
+	defer wt.Close()
+
+	conn := dial(t, wt)
+	defer conn.Close()
+	waitClients(t, wt, 1)
+	conn.Write([]byte("level>=warn pkg=github.com/foo/* ctx.reqid=abc\n"))
+
+	reqid := []iface.Context{{Key: "reqid", Value: "abc"}}
+	records := []iface.Record{
+		{Level: iface.Info, Pkg: "github.com/foo/db", Msg: "low level",
+			Aux: iface.Auxiliary{Contexts: reqid}},
+		{Level: iface.Warn, Pkg: "github.com/bar", Msg: "other pkg",
+			Aux: iface.Auxiliary{Contexts: reqid}},
+		{Level: iface.Warn, Pkg: "github.com/foo/db", Msg: "no context"},
+		{Level: iface.Error, Pkg: "github.com/foo/db", Msg: "matched",
+			Aux: iface.Auxiliary{Contexts: reqid}},
+	}
+
+	reader := bufio.NewReader(conn)
+	// the filter is applied asynchronously
+	for {
+		wt.Write([]byte("probe\n"), &iface.Record{Level: iface.Trace})
+		time.Sleep(10 * time.Millisecond)
+		if reader.Buffered() == 0 {
+			conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+			if _, err := reader.Peek(1); err != nil {
+				break
+			}
+		}
+		reader.Discard(reader.Buffered())
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	for i := range records {
+		wt.Write([]byte(records[i].Msg+"\n"), &records[i])
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "matched\n" {
+		t.Errorf("TestFilter:\noutput: %q\nexpect: %q", line, "matched\n")
+	}
+}
+
+func TestBacklog(t *testing.T) {
+	wt := open(t, tcp.Config{Options: tcp.Options{BacklogLogs: 2}})
+	defer wt.Close()
+
+	for _, msg := range []string{"evicted", "old", "older error", "newest"} {
+		level := iface.Info
+		if strings.Contains(msg, "error") {
+			level = iface.Error
+		}
+		wt.Write([]byte(msg+"\n"), &iface.Record{Level: level})
+	}
+
+	all := dial(t, wt)
+	defer all.Close()
+	filtered := dial(t, wt)
+	defer filtered.Close()
+	filtered.Write([]byte("level>=error\n"))
+	waitClients(t, wt, 2)
+	wt.Write([]byte("live error\n"), &iface.Record{Level: iface.Error})
+
+	checkLines(t, all, "older error\n", "newest\n", "live error\n")
+	checkLines(t, filtered, "older error\n", "live error\n")
+}
+
+func checkLines(t *testing.T, conn net.Conn, expect ...string) {
+	reader := bufio.NewReader(conn)
+	for _, line := range expect {
+		output, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output != line {
+			t.Errorf("checkLines:\noutput: %q\nexpect: %q", output, line)
+		}
+	}
+}
@@ -3,6 +3,21 @@ package unix
 import (
 	"os"
 	"strconv"
+
+	"github.com/fufuok/gxlog/writer/socket/internal/socket"
+)
+
+// Options are the options common to socket writers.
+type Options = socket.Options
+
+// The SlowPolicy defines the type of policy for subscribers that can NOT keep
+// up with logs.
+type SlowPolicy = socket.SlowPolicy
+
+// All available policies here, see socket.SlowPolicy for details.
+const (
+	DropWithNotice = socket.DropWithNotice
+	Disconnect     = socket.Disconnect
 )
 
 // A Config is used to configure a unix domain socket writer.
@@ -16,6 +31,9 @@ type Config struct {
 	Perm os.FileMode
 	// NoOverwrite specifies NOT to overwrite a existing socket file.
 	NoOverwrite bool
+	// Options are the options common to socket writers, e.g. the queue size
+	// and the backlog of subscribers.
+	Options
 }
 
 func (config *Config) setDefaults() {
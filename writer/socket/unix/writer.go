@@ -4,6 +4,11 @@
 // a syslog writer instead. With a unix domain socket writer, you can use `netcat'
 // to receive and watch logs rather than the `tail' which is inconvenient because
 // a new log file will be created when a log file reaches its max size.
+//
+// Subscribers are served as described in the documentation of package
+// github.com/fufuok/gxlog/writer/socket/internal/socket: each one has its own
+// queue of logs, may receive a backlog of the last logs when it connects and
+// may send lines of filter terms to receive only the logs it is interested in.
 package unix
 
 import (
@@ -15,6 +20,9 @@ import (
 	"github.com/fufuok/gxlog/writer/socket/internal/socket"
 )
 
+// Stats is the statistics of a Writer.
+type Stats = socket.Stats
+
 // A Writer implements the interface iface.Writer.
 //
 // All methods of a Writer are concurrency safe.
@@ -34,7 +42,7 @@ func Open(config Config) (*Writer, error) {
 	if err := os.MkdirAll(filepath.Dir(config.Pathname), config.Perm); err != nil {
 		return nil, openError(err)
 	}
-	writer, err := socket.Open("unix", config.Pathname)
+	writer, err := socket.Open("unix", config.Pathname, config.Options)
 	if err != nil {
 		return nil, openError(err)
 	}
@@ -58,6 +66,11 @@ func (writer *Writer) Write(bs []byte, record *iface.Record) {
 	writer.writer.Write(bs, record)
 }
 
+// Stats returns the statistics of the Writer.
+func (writer *Writer) Stats() Stats {
+	return writer.writer.Stats()
+}
+
 func openError(err error) error {
 	return fmt.Errorf("writer/socket/unix.Open: %v", err)
 }
@@ -0,0 +1,40 @@
+package sse
+
+import (
+	"time"
+)
+
+// A Config is used to configure a sse writer.
+type Config struct {
+	// Title is the title of the log viewer page.
+	// If Title is not specified, "gxlog" is used.
+	Title string
+	// MaxClients is the max count of connected browsers. Requests beyond it
+	// will be responded with 503 Service Unavailable.
+	// If MaxClients is not specified, 16 is used.
+	MaxClients int
+	// QueueSize is the capacity of the queue of logs of each browser. Logs are
+	// dropped while the queue is full, and then a notice of how many logs have
+	// been dropped is sent to the browser.
+	// If QueueSize is not specified, 256 is used.
+	QueueSize int
+	// Heartbeat is the interval of comments sent to keep idle connections alive
+	// through proxies.
+	// If Heartbeat is not specified, (15 * time.Second) is used.
+	Heartbeat time.Duration
+}
+
+func (config *Config) setDefaults() {
+	if config.Title == "" {
+		config.Title = "gxlog"
+	}
+	if config.MaxClients == 0 {
+		config.MaxClients = 16
+	}
+	if config.QueueSize == 0 {
+		config.QueueSize = 256
+	}
+	if config.Heartbeat == 0 {
+		config.Heartbeat = 15 * time.Second
+	}
+}
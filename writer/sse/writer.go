@@ -0,0 +1,319 @@
+// Package sse implements a writer which streams logs to browsers with
+// Server-Sent Events. A Writer is also an http.Handler which serves a live log
+// viewer page, such a developer can watch logs from a dev box or an internal
+// admin port without shell access to the host.
+//
+// The log viewer page renders the output of a json formatter with level colors,
+// and the output of the other formatters as plain text. Mount the Writer on an
+// http.ServeMux and link it with a json formatter:
+//
+//	wt := sse.New(sse.Config{})
+//	http.Handle("/logs", wt)
+//	log.Link(logger.Slot1, json.New(json.NewConfig()), wt)
+//
+// Query parameters of the page are passed through to its event stream to filter
+// logs per browser:
+//
+//	level=<level>  logs whose level is NOT lower than the level, e.g. level=warn
+//	pkg=<pkg>      logs of the pkg, or pkgs with the prefix if it ends with '*'
+//	filter=<terms> logs matching the terms in the syntax of the filter protocol
+//	               of the socket writers, e.g. filter=ctx.reqid=abc
+package sse
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/internal/filter"
+)
+
+type client struct {
+	queue   chan []byte
+	filter  filter.Filter
+	dropped int64 // accessed atomically
+}
+
+// A Writer implements the interface iface.Writer and http.Handler.
+//
+// All methods of a Writer are concurrency safe.
+// A Writer MUST be created with New.
+type Writer struct {
+	config  Config
+	page    []byte
+	clients map[*client]struct{}
+	closed  bool
+
+	lock sync.Mutex
+}
+
+// New creates a new Writer with the config.
+func New(config Config) *Writer {
+	config.setDefaults()
+	var page bytes.Buffer
+	pageTemplate.Execute(&page, config)
+	return &Writer{
+		config:  config,
+		page:    page.Bytes(),
+		clients: make(map[*client]struct{}),
+	}
+}
+
+// Close disconnects all the browsers. Requests after Close are responded with
+// 503 Service Unavailable.
+func (writer *Writer) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	writer.closed = true
+	for clt := range writer.clients {
+		close(clt.queue)
+		delete(writer.clients, clt)
+	}
+	return nil
+}
+
+// Write implements the interface Writer. It sends logs to the browsers whose
+// filters match them. The bs is copied since it may be released after Write
+// returns.
+func (writer *Writer) Write(bs []byte, record *iface.Record) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if len(writer.clients) > 0 {
+		bs = append(make([]byte, 0, len(bs)), bs...)
+	}
+	for clt := range writer.clients {
+		if clt.filter != nil && !clt.filter(record) {
+			continue
+		}
+		select {
+		case clt.queue <- bs:
+		default:
+			atomic.AddInt64(&clt.dropped, 1)
+		}
+	}
+}
+
+// Clients returns the count of connected browsers.
+func (writer *Writer) Clients() int {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return len(writer.clients)
+}
+
+// ServeHTTP implements the interface http.Handler. It serves the event stream
+// if the request accepts text/event-stream, otherwise the log viewer page.
+func (writer *Writer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		rw.Header().Set("Allow", "GET, HEAD")
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.Contains(req.Header.Get("Accept"), "text/event-stream") {
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		rw.Write(writer.page)
+		return
+	}
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		http.Error(rw, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	fn, err := parseQuery(req)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	clt := &client{
+		queue:  make(chan []byte, writer.config.QueueSize),
+		filter: fn,
+	}
+	if !writer.addClient(clt) {
+		http.Error(rw, "too many clients", http.StatusServiceUnavailable)
+		return
+	}
+	defer writer.removeClient(clt)
+
+	header := rw.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(writer.config.Heartbeat)
+	defer ticker.Stop()
+	var buf []byte
+	for {
+		select {
+		case bs, ok := <-clt.queue:
+			if !ok {
+				return
+			}
+			buf = buf[:0]
+			if dropped := atomic.SwapInt64(&clt.dropped, 0); dropped > 0 {
+				notice := fmt.Sprintf("%d logs dropped for the slow client", dropped)
+				buf = appendEvent(buf, "notice", []byte(notice))
+			}
+			buf = appendEvent(buf, "log", bs)
+		case <-ticker.C:
+			buf = append(buf[:0], ": ping\n\n"...)
+		case <-req.Context().Done():
+			return
+		}
+		if _, err := rw.Write(buf); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func (writer *Writer) addClient(clt *client) bool {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.closed || len(writer.clients) >= writer.config.MaxClients {
+		return false
+	}
+	writer.clients[clt] = struct{}{}
+	return true
+}
+
+func (writer *Writer) removeClient(clt *client) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if _, ok := writer.clients[clt]; ok {
+		close(clt.queue)
+		delete(writer.clients, clt)
+	}
+}
+
+func parseQuery(req *http.Request) (filter.Filter, error) {
+	query := req.URL.Query()
+	terms := []string{query.Get("filter")}
+	if level := query.Get("level"); level != "" {
+		terms = append(terms, "level>="+level)
+	}
+	if pkg := query.Get("pkg"); pkg != "" {
+		terms = append(terms, "pkg="+pkg)
+	}
+	return filter.Parse(strings.Join(terms, " "))
+}
+
+// appendEvent appends an event to the buf. Each line of the data becomes
+// a data field of the event.
+func appendEvent(buf []byte, event string, data []byte) []byte {
+	buf = append(buf, "event: "...)
+	buf = append(buf, event...)
+	buf = append(buf, '\n')
+	data = bytes.TrimRight(data, "\r\n")
+	for {
+		buf = append(buf, "data: "...)
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			buf = append(buf, data...)
+			break
+		}
+		buf = append(buf, bytes.TrimRight(data[:i], "\r")...)
+		buf = append(buf, '\n')
+		data = data[i+1:]
+	}
+	return append(buf, "\n\n"...)
+}
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; background: #1e1e1e; color: #d4d4d4; font: 13px monospace; }
+#bar { position: sticky; top: 0; padding: 6px 8px; background: #333; }
+#bar span { margin-left: 12px; color: #888; }
+#logs { padding: 4px 8px; white-space: pre-wrap; word-break: break-all; }
+.log { border-bottom: 1px solid #2a2a2a; }
+.time, .pos { color: #888; }
+.ctx { color: #4ec9b0; }
+.T, .D { color: #6a9955; } .I { color: #569cd6; } .W { color: #dcdcaa; }
+.E, .F { color: #f44747; } .marked { color: #c586c0; }
+.notice { color: #ce9178; font-style: italic; }
+</style>
+</head>
+<body>
+<div id="bar"><button id="pause">pause</button> <button id="clear">clear</button><span id="state">connecting</span></div>
+<div id="logs"></div>
+<script>
+(function() {
+  var logs = document.getElementById("logs");
+  var state = document.getElementById("state");
+  var paused = false, maxLogs = 5000;
+  document.getElementById("pause").onclick = function() {
+    paused = !paused;
+    this.textContent = paused ? "resume" : "pause";
+  };
+  document.getElementById("clear").onclick = function() { logs.textContent = ""; };
+  function span(cls, text) {
+    var el = document.createElement("span");
+    el.className = cls;
+    el.textContent = text;
+    return el;
+  }
+  function levelClass(level) {
+    if (typeof level === "number") { return "TDIWEF".charAt(level - 1); }
+    return String(level || "").charAt(0).toUpperCase();
+  }
+  function render(data) {
+    var div = document.createElement("div");
+    div.className = "log";
+    var rec;
+    try { rec = JSON.parse(data); } catch (e) { rec = null; }
+    if (!rec || typeof rec !== "object") {
+      div.textContent = data;
+      return div;
+    }
+    var cls = rec.marked ? "marked" : levelClass(rec.level);
+    if (rec.time !== undefined) { div.appendChild(span("time", rec.time + " ")); }
+    if (rec.level !== undefined) { div.appendChild(span(cls, rec.level + " ")); }
+    if (rec.file !== undefined) {
+      div.appendChild(span("pos", rec.file + ":" + rec.line + " " + rec.pkg + "." + rec.func + " "));
+    }
+    if (rec.prefix) { div.appendChild(span(cls, rec.prefix)); }
+    var ctx = rec.contexts;
+    if (ctx && typeof ctx === "object") {
+      var pairs = [];
+      (Array.isArray(ctx) ? ctx : [ctx]).forEach(function(obj) {
+        for (var k in obj) { pairs.push(k + "=" + obj[k]); }
+      });
+      if (pairs.length) { div.appendChild(span("ctx", "[" + pairs.join(" ") + "] ")); }
+    }
+    div.appendChild(span(cls, rec.msg !== undefined ? rec.msg : data));
+    return div;
+  }
+  function append(el) {
+    if (paused) { return; }
+    var bottom = window.innerHeight + window.scrollY >= document.body.scrollHeight - 4;
+    logs.appendChild(el);
+    while (logs.childNodes.length > maxLogs) { logs.removeChild(logs.firstChild); }
+    if (bottom) { window.scrollTo(0, document.body.scrollHeight); }
+  }
+  var source = new EventSource(location.pathname + location.search);
+  source.onopen = function() { state.textContent = "connected"; };
+  source.onerror = function() { state.textContent = "reconnecting"; };
+  source.addEventListener("log", function(e) { append(render(e.data)); });
+  source.addEventListener("notice", function(e) {
+    append(span("log notice", e.data));
+  });
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,63 @@
+package sse_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/sse"
+)
+
+func TestPage(t *testing.T) {
+	wt := sse.New(sse.Config{Title: "demo"})
+	rec := httptest.NewRecorder()
+	wt.ServeHTTP(rec, httptest.NewRequest("GET", "/logs", nil))
+	if !strings.Contains(rec.Body.String(), "<title>demo</title>") {
+		t.Errorf("TestPage: unexpected page:\n%s", rec.Body.String())
+	}
+}
+
+func TestStream(t *testing.T) {
+	wt := sse.New(sse.Config{})
+	defer wt.Close()
+	server := httptest.NewServer(wt)
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL+"?level=warn&pkg=main", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for wt.Clients() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("the client is not connected")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	wt.Write([]byte("info\n"), &iface.Record{Level: iface.Info, Pkg: "main"})
+	wt.Write([]byte("other\n"), &iface.Record{Level: iface.Warn, Pkg: "other"})
+	wt.Write([]byte("multi\nline\n"), &iface.Record{Level: iface.Error, Pkg: "main"})
+
+	expect := []string{"event: log", "data: multi", "data: line", ""}
+	reader := bufio.NewReader(resp.Body)
+	for _, line := range expect {
+		output, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output != line+"\n" {
+			t.Errorf("TestStream:\noutput: %q\nexpect: %q", output, line+"\n")
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package syslog
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/fufuok/gxlog/iface"
 	"github.com/fufuok/gxlog/writer"
@@ -42,8 +48,25 @@ const (
 	SevDebug
 )
 
+// The Framing defines the type of framing of syslog messages, see RFC 6587.
+type Framing int
+
+// All available framings here.
+const (
+	// Messages are sent as they are. It is suitable for datagram networks only.
+	NoFraming Framing = iota
+	// Each message is prefixed with its length and a space. Trailing line feeds
+	// of a message are removed and NOT counted, line feeds inside it are kept.
+	// It is RECOMMENDED for stream networks, e.g. tcp.
+	OctetCounting
+	// Each message is terminated with a '\n'. Line feeds inside a message, e.g.
+	// stacks output with logs, are escaped as "#012".
+	NonTransparent
+)
+
 // A Config is used to configure a syslog writer.
 type Config struct {
+	// Tag is the TAG of syslog messages, it is the APP-NAME in RFC5424Format.
 	// If Tag is not specified, filepath.Base(os.Args[0]) is used.
 	Tag string
 	// If Facility is not specified, FacKern is used.
@@ -53,6 +76,41 @@ type Config struct {
 	Network string
 	// Addr will be passed to net.Dial if Network is not empty.
 	Addr string
+	// Framing specifies how messages are delimited. Use OctetCounting or
+	// NonTransparent with stream networks, e.g. tcp.
+	// If Framing is not specified, NoFraming is used.
+	Framing Framing
+	// TLSConfig is used to dial with TLS (RFC 5425) if it is not nil. Network
+	// MUST be a stream network, e.g. tcp.
+	TLSConfig *tls.Config
+	// CAFile is the pathname of PEM encoded CA certificates to verify the
+	// syslog server. If TLSConfig is nil and any of CAFile, CertFile and KeyFile
+	// is specified, TLSConfig is built with them and the host of Addr as the
+	// server name.
+	CAFile string
+	// CertFile is the pathname of the PEM encoded client certificate.
+	// It MUST be specified with KeyFile.
+	CertFile string
+	// KeyFile is the pathname of the PEM encoded private key of the client
+	// certificate. It MUST be specified with CertFile.
+	KeyFile string
+	// DialTimeout is the timeout of dialing.
+	// If DialTimeout is not specified, (5 * time.Second) is used.
+	DialTimeout time.Duration
+	// WriteTimeout is the timeout of writing a message. If it is negative,
+	// there is no timeout.
+	// If WriteTimeout is not specified, (5 * time.Second) is used.
+	WriteTimeout time.Duration
+	// MinBackoff is the interval before the next reconnection after a failed
+	// one. It doubles after each failed reconnection until it reaches
+	// MaxBackoff. Logs written during the interval are reported to the
+	// ErrorHandler without dialing. Wrap the writer with a retry writer to
+	// avoid losing them.
+	// If MinBackoff is not specified, (100 * time.Millisecond) is used.
+	MinBackoff time.Duration
+	// MaxBackoff is the max interval between reconnections.
+	// If MaxBackoff is not specified, (30 * time.Second) is used.
+	MaxBackoff time.Duration
 	// SeverityMap is used to remap the severity of levels.
 	// The severity of a level is left to be unchanged if it is not in the map.
 	// The default mapping is as the follows:
@@ -63,6 +121,26 @@ type Config struct {
 	//   Error: SevErr
 	//   Fatal: SevCrit
 	SeverityMap map[iface.Level]Severity
+	// Format is the format of syslog messages.
+	// If Format is not specified, BSDFormat is used.
+	Format Format
+	// Hostname is the HOSTNAME of syslog messages sent to a remote syslog server
+	// or in RFC5424Format.
+	// If Hostname is not specified, os.Hostname() is used.
+	Hostname string
+	// MsgID is the MSGID of syslog messages in RFC5424Format.
+	// If MsgID is not specified, the NILVALUE "-" is used.
+	MsgID string
+	// PrefixAsMsgID specifies to use the prefix of a log as the MSGID of the
+	// syslog message in RFC5424Format if the prefix is not empty. Spaces around
+	// the prefix are trimmed.
+	PrefixAsMsgID bool
+	// SDID is the SD-ID of the SD-ELEMENT in which the contexts of a log become
+	// SD-PARAMs in RFC5424Format. The SD-ID of a custom SD-ELEMENT MUST be in the
+	// format name@<private enterprise number>.
+	// If SDID is not specified, "gxlog@32473" is used, where 32473 is the
+	// private enterprise number reserved for documentation.
+	SDID string
 	// ErrorHandler will be called when an error occurs if it is not nil.
 	ErrorHandler writer.ErrorHandler
 }
@@ -71,4 +149,67 @@ func (config *Config) setDefaults() {
 	if config.Tag == "" {
 		config.Tag = filepath.Base(os.Args[0])
 	}
+	if config.SDID == "" {
+		config.SDID = "gxlog@32473"
+	}
+	if config.DialTimeout == 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	if config.WriteTimeout == 0 {
+		config.WriteTimeout = 5 * time.Second
+	}
+	if config.MinBackoff == 0 {
+		config.MinBackoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+}
+
+func (config *Config) check() error {
+	if config.Framing < NoFraming || config.Framing > NonTransparent {
+		return errors.New("Config.Framing is invalid")
+	}
+	if config.DialTimeout < 0 {
+		return errors.New("Config.DialTimeout must NOT be negative")
+	}
+	if config.MinBackoff < 0 || config.MaxBackoff < config.MinBackoff {
+		return errors.New("Config.MinBackoff or Config.MaxBackoff is invalid")
+	}
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return errors.New("Config.CertFile and Config.KeyFile must be specified together")
+	}
+	return nil
+}
+
+func (config *Config) loadTLSConfig() error {
+	if config.TLSConfig != nil ||
+		config.CAFile == "" && config.CertFile == "" && config.KeyFile == "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(config.Addr)
+	if err != nil {
+		return err
+	}
+	tlsConfig := &tls.Config{ServerName: host}
+	if config.CAFile != "" {
+		pem, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("no certificate found in Config.CAFile")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	config.TLSConfig = tlsConfig
+	return nil
 }
@@ -0,0 +1,161 @@
+package syslog
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+)
+
+// The Format defines the type of syslog message format.
+type Format int
+
+// All available formats here.
+const (
+	// <PRI>Stamp TAG[PID]: MSG to the local syslog server, and
+	// <PRI>RFC3339 HOSTNAME TAG[PID]: MSG to a remote syslog server.
+	BSDFormat Format = iota
+	// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG,
+	// see RFC 5424.
+	RFC5424Format
+)
+
+const (
+	rfc5424Layout = "2006-01-02T15:04:05.000000Z07:00"
+	nilValue      = "-"
+	maxHostname   = 255
+	maxAppName    = 48
+	maxProcID     = 128
+	maxMsgID      = 32
+	maxSDName     = 32
+)
+
+type header struct {
+	Priority int
+	Hostname string
+	Tag      string
+	ProcID   string
+	MsgID    string
+	SDID     string
+}
+
+func appendBSD(buf []byte, hdr *header, local bool, msg []byte,
+	record *iface.Record) []byte {
+
+	buf = appendPriority(buf, hdr.Priority)
+	if local {
+		buf = record.Time.AppendFormat(buf, time.Stamp)
+	} else {
+		buf = record.Time.AppendFormat(buf, time.RFC3339)
+		buf = append(buf, ' ')
+		buf = append(buf, hdr.Hostname...)
+	}
+	buf = append(buf, ' ')
+	buf = append(buf, hdr.Tag...)
+	buf = append(buf, '[')
+	buf = append(buf, hdr.ProcID...)
+	buf = append(buf, "]: "...)
+	return append(buf, msg...)
+}
+
+func appendRFC5424(buf []byte, hdr *header, msg []byte, record *iface.Record) []byte {
+	buf = appendPriority(buf, hdr.Priority)
+	buf = append(buf, '1', ' ')
+	buf = record.Time.AppendFormat(buf, rfc5424Layout)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, hdr.Hostname, maxHostname)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, hdr.Tag, maxAppName)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, hdr.ProcID, maxProcID)
+	buf = append(buf, ' ')
+	buf = appendHeaderField(buf, hdr.MsgID, maxMsgID)
+	buf = append(buf, ' ')
+	buf = appendStructuredData(buf, hdr.SDID, record.Aux.Contexts)
+	if len(msg) > 0 {
+		buf = append(buf, ' ')
+		buf = append(buf, msg...)
+	}
+	return buf
+}
+
+func appendPriority(buf []byte, priority int) []byte {
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(priority), 10)
+	return append(buf, '>')
+}
+
+// appendHeaderField appends the str as a header field which consists of at most
+// max printable US-ASCII characters. Other characters are replaced with '_'.
+func appendHeaderField(buf []byte, str string, max int) []byte {
+	if str == "" {
+		return append(buf, nilValue...)
+	}
+	if len(str) > max {
+		str = str[:max]
+	}
+	for i := 0; i < len(str); i++ {
+		if isPrintASCII(str[i]) {
+			buf = append(buf, str[i])
+		} else {
+			buf = append(buf, '_')
+		}
+	}
+	return buf
+}
+
+// appendStructuredData appends the contexts as SD-PARAMs of one SD-ELEMENT
+// with the id. It appends the NILVALUE if there is no context.
+func appendStructuredData(buf []byte, id string, contexts []iface.Context) []byte {
+	if len(contexts) == 0 || id == "" {
+		return append(buf, nilValue...)
+	}
+	buf = append(buf, '[')
+	buf = appendSDName(buf, id)
+	for _, context := range contexts {
+		buf = append(buf, ' ')
+		buf = appendSDName(buf, context.Key)
+		buf = append(buf, '=', '"')
+		buf = appendSDValue(buf, context.Value)
+		buf = append(buf, '"')
+	}
+	return append(buf, ']')
+}
+
+// appendSDName appends the str as an SD-NAME which consists of at most 32
+// printable US-ASCII characters except '=', ' ', ']' and '"'. Other characters
+// are replaced with '_'.
+func appendSDName(buf []byte, str string) []byte {
+	if str == "" {
+		return append(buf, '_')
+	}
+	if len(str) > maxSDName {
+		str = str[:maxSDName]
+	}
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; {
+		case c == '=' || c == ']' || c == '"' || !isPrintASCII(c):
+			buf = append(buf, '_')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// appendSDValue appends the str as a PARAM-VALUE with '"', '\' and ']' escaped.
+func appendSDValue(buf []byte, str string) []byte {
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; c {
+		case '"', '\\', ']':
+			buf = append(buf, '\\', c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+func isPrintASCII(c byte) bool {
+	return c > ' ' && c < 0x7f
+}
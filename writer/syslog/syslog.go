@@ -1,29 +1,29 @@
 package syslog
 
 import (
+	"bytes"
+	"crypto/tls"
 	"errors"
-	"fmt"
 	"net"
-	"os"
+	"strconv"
 	"time"
+
+	"github.com/fufuok/gxlog/writer/internal/backoff"
 )
 
+var errBackoff = errors.New("waiting to reconnect")
+
 type syslog struct {
-	network string
-	addr    string
-	host    string
+	config  *Config
 	conn    net.Conn
+	backoff *backoff.Backoff
+	buf     []byte
 }
 
-func syslogDial(network, addr string) (*syslog, error) {
-	host, err := os.Hostname()
-	if err != nil {
-		return nil, err
-	}
+func syslogDial(config *Config) (*syslog, error) {
 	log := &syslog{
-		network: network,
-		addr:    addr,
-		host:    host,
+		config:  config,
+		backoff: backoff.New(config.MinBackoff, config.MaxBackoff),
 	}
 	if err := log.connect(); err != nil {
 		return nil, err
@@ -31,18 +31,24 @@ func syslogDial(network, addr string) (*syslog, error) {
 	return log, nil
 }
 
-func (log *syslog) Write(timestamp time.Time, priority int, tag string, msg []byte) error {
+func (log *syslog) Write(msg []byte) error {
 	if log.conn != nil {
-		if err := log.write(timestamp, priority, tag, msg); err == nil {
+		if err := log.write(msg); err == nil {
 			return nil
-		} else {
-			log.Close()
 		}
+		// the connection may be stale, reconnect at once for the first failure
+		log.Close()
+		log.backoff.Reset()
 	}
 	if err := log.connect(); err != nil {
 		return err
 	}
-	return log.write(timestamp, priority, tag, msg)
+	if err := log.write(msg); err != nil {
+		log.Close()
+		log.backoff.Fail(time.Now())
+		return err
+	}
+	return nil
 }
 
 func (log *syslog) Close() error {
@@ -55,38 +61,70 @@ func (log *syslog) Close() error {
 }
 
 func (log *syslog) connect() error {
+	now := time.Now()
+	if !log.backoff.Ready(now) {
+		return errBackoff
+	}
 	var conn net.Conn
 	var err error
-	if log.network == "" {
-		conn, err = dialLocal()
-	} else {
-		conn, err = net.Dial(log.network, log.addr)
+	dialer := &net.Dialer{Timeout: log.config.DialTimeout}
+	switch {
+	case log.config.Network == "":
+		conn, err = dialLocal(dialer)
+	case log.config.TLSConfig != nil:
+		conn, err = tls.DialWithDialer(dialer, log.config.Network, log.config.Addr,
+			log.config.TLSConfig)
+	default:
+		conn, err = dialer.Dial(log.config.Network, log.config.Addr)
 	}
 	if err != nil {
+		log.backoff.Fail(now)
 		return err
 	}
+	log.backoff.Reset()
 	log.conn = conn
 	return nil
 }
 
-func (log *syslog) write(timestamp time.Time, priority int, tag string, msg []byte) error {
-	var err error
-	if log.network == "" {
-		_, err = fmt.Fprintf(log.conn, "<%d>%s %s[%d]: %s",
-			priority, timestamp.Format(time.Stamp), tag, os.Getpid(), msg)
-	} else {
-		_, err = fmt.Fprintf(log.conn, "<%d>%s %s %s[%d]: %s",
-			priority, timestamp.Format(time.RFC3339), log.host, tag, os.Getpid(), msg)
+func (log *syslog) write(msg []byte) error {
+	log.buf = frame(log.buf[:0], msg, log.config.Framing)
+	if log.config.WriteTimeout > 0 {
+		log.conn.SetWriteDeadline(time.Now().Add(log.config.WriteTimeout))
 	}
+	_, err := log.conn.Write(log.buf)
 	return err
 }
 
-func dialLocal() (net.Conn, error) {
+func frame(buf, msg []byte, framing Framing) []byte {
+	switch framing {
+	case OctetCounting:
+		msg = bytes.TrimRight(msg, "\n")
+		buf = strconv.AppendInt(buf, int64(len(msg)), 10)
+		buf = append(buf, ' ')
+		return append(buf, msg...)
+	case NonTransparent:
+		msg = bytes.TrimRight(msg, "\n")
+		for {
+			i := bytes.IndexByte(msg, '\n')
+			if i < 0 {
+				break
+			}
+			buf = append(buf, msg[:i]...)
+			buf = append(buf, "#012"...)
+			msg = msg[i+1:]
+		}
+		buf = append(buf, msg...)
+		return append(buf, '\n')
+	}
+	return append(buf, msg...)
+}
+
+func dialLocal(dialer *net.Dialer) (net.Conn, error) {
 	networks := []string{"unixgram", "unix"}
 	paths := []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
 	for _, network := range networks {
 		for _, path := range paths {
-			conn, err := net.Dial(network, path)
+			conn, err := dialer.Dial(network, path)
 			if err == nil {
 				return conn, nil
 			}
@@ -6,6 +6,9 @@ package syslog
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/fufuok/gxlog/iface"
@@ -21,8 +24,13 @@ type Writer struct {
 	tag          string
 	errorHandler writer.ErrorHandler
 
-	severities []Severity
-	log        *syslog
+	severities    []Severity
+	log           *syslog
+	format        Format
+	local         bool
+	hdr           header
+	prefixAsMsgID bool
+	buf           []byte
 
 	lock sync.Mutex
 }
@@ -32,7 +40,20 @@ type Writer struct {
 // socket.
 func Open(config Config) (*Writer, error) {
 	config.setDefaults()
-	log, err := syslogDial(config.Network, config.Addr)
+	if config.Hostname == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("writer/syslog.Open: %v", err)
+		}
+		config.Hostname = host
+	}
+	if err := config.check(); err != nil {
+		return nil, fmt.Errorf("writer/syslog.Open: %v", err)
+	}
+	if err := config.loadTLSConfig(); err != nil {
+		return nil, fmt.Errorf("writer/syslog.Open: %v", err)
+	}
+	log, err := syslogDial(&config)
 	if err != nil {
 		return nil, fmt.Errorf("writer/syslog.Open: %v", err)
 	}
@@ -50,6 +71,15 @@ func Open(config Config) (*Writer, error) {
 		errorHandler: config.ErrorHandler,
 		severities:   severities,
 		log:          log,
+		format:       config.Format,
+		local:        config.Network == "",
+		hdr: header{
+			Hostname: config.Hostname,
+			ProcID:   strconv.Itoa(os.Getpid()),
+			MsgID:    config.MsgID,
+			SDID:     config.SDID,
+		},
+		prefixAsMsgID: config.PrefixAsMsgID,
 	}
 	writer.MapSeverities(config.SeverityMap)
 	return writer, nil
@@ -71,17 +101,20 @@ func (writer *Writer) Write(bs []byte, record *iface.Record) {
 	writer.lock.Lock()
 	defer writer.lock.Unlock()
 
-	severity := writer.severities[record.Level]
-	priority := int(writer.facility) | int(severity)
-	err := writer.log.Write(record.Time, priority, writer.tag, bs)
-	if err != nil {
-		writer.log.Close()
-		if writer.errorHandler != nil {
-			writer.errorHandler(bs, record, err)
-		}
+	if err := writer.send(bs, record); err != nil && writer.errorHandler != nil {
+		writer.errorHandler(bs, record, err)
 	}
 }
 
+// Send implements the interface writer.Sender. It does the same with Write
+// except that it returns the error instead of calling the error handler.
+func (writer *Writer) Send(bs []byte, record *iface.Record) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.send(bs, record)
+}
+
 // Facility returns the facility of the Writer.
 func (writer *Writer) Facility() Facility {
 	writer.lock.Lock()
@@ -140,3 +173,25 @@ func (writer *Writer) MapSeverities(severityMap map[iface.Level]Severity) {
 		writer.severities[level] = severity
 	}
 }
+
+func (writer *Writer) send(bs []byte, record *iface.Record) error {
+	severity := writer.severities[record.Level]
+	hdr := writer.hdr
+	hdr.Priority = int(writer.facility) | int(severity)
+	hdr.Tag = writer.tag
+	if writer.prefixAsMsgID {
+		if prefix := strings.TrimSpace(record.Aux.Prefix); prefix != "" {
+			hdr.MsgID = prefix
+		}
+	}
+	if writer.format == RFC5424Format {
+		writer.buf = appendRFC5424(writer.buf[:0], &hdr, bs, record)
+	} else {
+		writer.buf = appendBSD(writer.buf[:0], &hdr, writer.local, bs, record)
+	}
+	err := writer.log.Write(writer.buf)
+	if err != nil {
+		writer.log.Close()
+	}
+	return err
+}
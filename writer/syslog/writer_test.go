@@ -0,0 +1,234 @@
+package syslog_test
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fufuok/gxlog/iface"
+	"github.com/fufuok/gxlog/writer/syslog"
+)
+
+var tmplRecord = iface.Record{
+	Time:  time.Date(2018, 8, 1, 7, 12, 7, 235605270, time.UTC),
+	Level: iface.Warn,
+	Msg:   "testing",
+	Aux: iface.Auxiliary{
+		Prefix: " **** ",
+		Contexts: []iface.Context{
+			{Key: "k1", Value: `a "quoted" \ value]`},
+			{Key: "bad key=", Value: "v2"},
+		},
+	},
+}
+
+func TestRFC5424(t *testing.T) {
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	wt, err := syslog.Open(syslog.Config{
+		Tag:           "app",
+		Facility:      syslog.FacUser,
+		Network:       "udp",
+		Addr:          conn.LocalAddr().String(),
+		Format:        syslog.RFC5424Format,
+		Hostname:      "host",
+		PrefixAsMsgID: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+
+	if err := wt.Send([]byte("testing\n"), &tmplRecord); err != nil {
+		t.Fatal(err)
+	}
+	expect := fmt.Sprintf("<12>1 2018-08-01T07:12:07.235605Z host app %d **** "+
+		`[gxlog@32473 k1="a \"quoted\" \\ value\]" bad_key_="v2"] testing`+"\n",
+		os.Getpid())
+	checkMessage(t, conn, expect)
+
+	record := tmplRecord
+	record.Aux = iface.Auxiliary{}
+	if err := wt.Send([]byte("testing"), &record); err != nil {
+		t.Fatal(err)
+	}
+	expect = fmt.Sprintf("<12>1 2018-08-01T07:12:07.235605Z host app %d - - testing",
+		os.Getpid())
+	checkMessage(t, conn, expect)
+}
+
+func listenUDP(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func checkMessage(t *testing.T, conn net.PacketConn, expect string) {
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output := string(buf[:n]); output != expect {
+		t.Errorf("checkMessage:\noutput: %q\nexpect: %q", output, expect)
+	}
+}
+
+func TestFraming(t *testing.T) {
+	testCases := []struct {
+		Framing syslog.Framing
+		Expect  string
+	}{
+		{syslog.OctetCounting, "%d <12>2018-08-01T07:12:07Z host app[%d]: a\nb"},
+		{syslog.NonTransparent, "<12>2018-08-01T07:12:07Z host app[%d]: a#012b\n"},
+	}
+	for _, tc := range testCases {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		wt, err := syslog.Open(syslog.Config{
+			Tag:      "app",
+			Facility: syslog.FacUser,
+			Network:  "tcp",
+			Addr:     listener.Addr().String(),
+			Hostname: "host",
+			Framing:  tc.Framing,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		record := tmplRecord
+		record.Aux = iface.Auxiliary{}
+		if err := wt.Send([]byte("a\nb\n"), &record); err != nil {
+			t.Fatal(err)
+		}
+		pid := os.Getpid()
+		expect := fmt.Sprintf(tc.Expect, pid)
+		if tc.Framing == syslog.OctetCounting {
+			msg := fmt.Sprintf("<12>2018-08-01T07:12:07Z host app[%d]: a\nb", pid)
+			expect = fmt.Sprintf(tc.Expect, len(msg), pid)
+		}
+		buf := make([]byte, len(expect))
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != expect {
+			t.Errorf("TestFraming:\noutput: %q\nexpect: %q", buf, expect)
+		}
+		wt.Close()
+		conn.Close()
+		listener.Close()
+	}
+}
+
+func TestOctetCounting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	wt, err := syslog.Open(syslog.Config{
+		Tag:      "app",
+		Facility: syslog.FacUser,
+		Network:  "tcp",
+		Addr:     listener.Addr().String(),
+		Hostname: "host",
+		Framing:  syslog.OctetCounting,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	record := tmplRecord
+	record.Aux = iface.Auxiliary{}
+	for _, msg := range []string{"a\nb\n\n", "c\n"} {
+		if err := wt.Send([]byte(msg), &record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pid := strconv.Itoa(os.Getpid())
+	// "<12>2018-08-01T07:12:07Z host app[" is 34 octets and "]: a\nb" is 6
+	first := 34 + len(pid) + 6
+	expect := fmt.Sprintf("%d <12>2018-08-01T07:12:07Z host app[%s]: a\nb"+
+		"%d <12>2018-08-01T07:12:07Z host app[%s]: c", first, pid, first-2, pid)
+	buf := make([]byte, len(expect))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != expect {
+		t.Errorf("TestOctetCounting:\noutput: %q\nexpect: %q", buf, expect)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	wt, err := syslog.Open(syslog.Config{
+		Network:    "tcp",
+		Addr:       listener.Addr().String(),
+		Framing:    syslog.NonTransparent,
+		MinBackoff: time.Hour,
+		MaxBackoff: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	chanConn := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			chanConn <- conn
+		}
+	}()
+	// The peer closing is noticed by a failed write at some point, after which
+	// the writer reconnects at once.
+	var sendErr error
+	for i := 0; i < 100; i++ {
+		if sendErr = wt.Send([]byte("testing"), &tmplRecord); sendErr != nil {
+			break
+		}
+		select {
+		case conn := <-chanConn:
+			conn.Close()
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if sendErr != nil {
+		t.Fatalf("TestReconnect: %v", sendErr)
+	}
+	t.Fatal("TestReconnect: no reconnection")
+}
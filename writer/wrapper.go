@@ -2,6 +2,7 @@ package writer
 
 import (
 	"io"
+	"sync"
 
 	"github.com/fufuok/gxlog/iface"
 )
@@ -9,9 +10,13 @@ import (
 type Wrapper struct {
 	writer  io.Writer
 	handler ErrorHandler
+
+	lock sync.Mutex
 }
 
 // Wrap wraps an io.Writer to iface.Writer. The writer must NOT be nil.
+// Calls to the Write of the writer are serialized, so the writer needs not to
+// be concurrency safe.
 func Wrap(writer io.Writer, handler ErrorHandler) iface.Writer {
 	return &Wrapper{
 		writer:  writer,
@@ -20,7 +25,9 @@ func Wrap(writer io.Writer, handler ErrorHandler) iface.Writer {
 }
 
 func (wrapper *Wrapper) Write(bs []byte, record *iface.Record) {
+	wrapper.lock.Lock()
 	_, err := wrapper.writer.Write(bs)
+	wrapper.lock.Unlock()
 	if err != nil && wrapper.handler != nil {
 		wrapper.handler(bs, record, err)
 	}